@@ -1,328 +1,2008 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math"
+	"mime/multipart"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
 	"path"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
 )
 
 type Auth struct {
 	AdminKey      string `json:"admin_key"`
 	ServerAddress string `json:"server_address"`
+	// APIVersion 为 Admin API 版本（v2/v3），决定请求头与响应结构
+	APIVersion string `json:"api_version"`
+	// LegacySNI 为 true 时单域名证书使用旧版标量 sni 字段上传
+	LegacySNI bool `json:"legacy_sni"`
+	// IDPaths 为从创建响应中读取 id 的候选路径（如 data.value.id），依次尝试，均未命中时使用内置规则
+	IDPaths []string `json:"id_path"`
+	// APIDetected 为 true 表示 APIVersion/LegacySNI 由 api_version=auto 探测得出
+	APIDetected bool `json:"-"`
+	// BodyFormat 为请求体格式：json（默认）或 multipart
+	BodyFormat string `json:"body_format"`
+	// CompressUpload 为 true 时，超过 compressMinSize 的请求体以 gzip 压缩发送（Content-Encoding: gzip）
+	CompressUpload bool `json:"compress_upload"`
+	// TTLSeconds 大于 0 时创建/更新的 SSL 对象在该秒数后自动过期，仅 APISIX 2.x 支持
+	TTLSeconds int `json:"ttl_seconds"`
+	// MaxRetries 为遇到 429 限流时的最大重试次数
+	MaxRetries int `json:"max_retries"`
+	// Timeout 为单次 HTTP 请求的超时时间
+	Timeout time.Duration `json:"timeout"`
+	// GatewayGroup 为目标网关分组（APISIX 企业版等多租户控制面），为空表示不指定
+	GatewayGroup string `json:"gateway_group"`
+	// GatewayGroupMode 为分组的传递方式：header（默认，X-Gateway-Group 请求头）或 path（/gateway_groups/<group> 路径段）
+	GatewayGroupMode string `json:"gateway_group_mode"`
+	// AdminPrefix 为拼接在 ServerAddress 之后的 Admin API 路径前缀，ServerAddress 已包含前缀时为空
+	AdminPrefix string `json:"admin_prefix"`
+	// TLSConfig 为访问 Admin API 使用的 TLS 配置，为 nil 时使用默认配置
+	TLSConfig *tls.Config `json:"-"`
+	// MaxIdleConns 为连接池保留的最大空闲连接数
+	MaxIdleConns int `json:"max_idle_conns"`
+	// IdleConnTimeout 为空闲连接的保留时间
+	IdleConnTimeout time.Duration `json:"idle_conn_timeout"`
+	// httpClient 为发送请求使用的客户端，由 NewAuth 构造或来自 WithHTTPClient
+	httpClient *http.Client
+	// transport 为同一 Auth 共享的连接池
+	transport *http.Transport
+	// OpTimeouts 为单次 API 操作（含 429 重试等待）的截止时间，key 为 HTTP method，"*" 为默认值
+	OpTimeouts map[string]time.Duration `json:"-"`
+	// AuthScheme 为 AdminKey 的传递方式：x-api-key（默认，X-API-KEY 请求头）或 bearer（Authorization: Bearer）
+	AuthScheme string `json:"auth_scheme"`
+	// BasicUser/BasicPass 非空时额外携带 HTTP Basic 认证，用于 Admin API 前置了反向代理认证的部署；
+	// 不参与 JSON 序列化，避免出现在任何输出中
+	BasicUser string `json:"-"`
+	BasicPass string `json:"-"`
+	// KeySource 记录 admin_key 的来源（params/file/env），用于排查配置
+	KeySource string `json:"-"`
+	// timings 非 nil 时按 "METHOD path" 累计 API 调用次数与耗时
+	timings *timingLog
+	// raw 非 nil 时记录每次 API 调用的原始响应（私钥已脱敏）
+	raw *[]map[string]any
+	// retry 记录最近一次发生过重试的调用，用于输出 retry_info
+	retry *retryRecord
+	// ctx 为所有 API 调用的父 context，取消后正在进行与后续的调用立即失败
+	ctx context.Context
 }
 
-func NewAuth(adminKey, serverAddress string) *Auth {
-	return &Auth{
-		AdminKey:      adminKey,
-		ServerAddress: serverAddress,
+// WithContext 返回使用 ctx 作为父 context 的副本，批量操作可借此在致命错误时中止
+func (a Auth) WithContext(ctx context.Context) *Auth {
+	a.ctx = ctx
+	return &a
+}
+
+// RawResponses 返回 debug 模式下记录的原始响应
+func (a Auth) RawResponses() []map[string]any {
+	if a.raw == nil {
+		return nil
 	}
+	return *a.raw
 }
 
-func Upload_bind(cfg map[string]any) (*Response, error) {
+// Timings 返回 verbose 模式下按 "METHOD path" 汇总的 API 调用耗时
+func (a Auth) Timings() map[string]callTiming {
+	if a.timings == nil {
+		return nil
+	}
+	return a.timings.snapshot()
+}
+
+// 默认使用 APISIX 3.x 的 Admin API 响应结构
+const defaultAPIVersion = "v3"
+
+// 默认遇到 429 限流时最多重试 3 次
+const defaultMaxRetries = 3
+
+// 默认单次请求超时时间
+const defaultTimeout = 30 * time.Second
+
+// 未在参数中提供 admin_key 时读取的环境变量
+const adminKeyEnv = "APISIX_ADMIN_KEY"
+
+// envDefaults 为可由环境变量提供默认值的参数，网关拓扑统一的部署无需在每个请求中重复传入；
+// 请求参数中显式提供的值优先
+var envDefaults = map[string]string{
+	"admin_prefix": "ALLINSSL_ADMIN_PREFIX",
+	"backend":      "ALLINSSL_BACKEND",
+}
+
+// applyEnvDefaults 为未提供的参数填入环境变量中的默认值，返回实际生效的参数及其环境变量名
+func applyEnvDefaults(cfg map[string]any) map[string]string {
+	applied := map[string]string{}
+	for name, env := range envDefaults {
+		if v, _ := cfg[name].(string); v != "" {
+			continue
+		}
+		if v := os.Getenv(env); v != "" {
+			cfg[name] = v
+			applied[name] = env
+		}
+	}
+	return applied
+}
+
+// NewAuth 使用默认配置构造 Auth，可通过 AuthOption 调整超时、重试、TLS 等行为
+func NewAuth(adminKey, serverAddress string, opts ...AuthOption) *Auth {
+	a := &Auth{
+		AdminKey:        adminKey,
+		ServerAddress:   serverAddress,
+		APIVersion:      defaultAPIVersion,
+		MaxRetries:      defaultMaxRetries,
+		Timeout:         defaultTimeout,
+		MaxIdleConns:    defaultMaxIdleConns,
+		IdleConnTimeout: defaultIdleConnTimeout,
+		KeySource:       "params",
+		retry:           &retryRecord{},
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	// 所有选项应用完毕后构造一次 transport 与 client，此后不再变更
+	a.transport = a.newTransport()
+	a.httpClient = a.buildClient()
+	return a
+}
+
+// 复用已有证书、未做任何修改时响应的 Code，便于自动化区分"无需变更"与"已变更"
+const codeNoChange = "no_change"
+
+// 由插件托管的证书统一使用该 desc 前缀
+const managedPrefix = "allinssl-"
+
+// managed_by_field 为 labels 时，托管标记写入该 label 而不是 desc
+const managedByLabel = "managed-by"
+
+// managedByField 指定识别托管证书的字段：desc（默认）或 labels。
+// 使用 labels 时 desc 留给用户自己的备注，托管标记写入 managed-by label
+var managedByField = "desc"
+
+// resolveAdminKey 按优先级读取 admin_key：参数 admin_key > admin_key_file > APISIX_ADMIN_KEY 环境变量，
+// 同时返回来源（params/file/env）
+func resolveAdminKey(cfg map[string]any) (string, string, error) {
+	if adminKey, ok := cfg["admin_key"].(string); ok && adminKey != "" {
+		return adminKey, "params", nil
+	}
+	if keyFile, ok := cfg["admin_key_file"].(string); ok && keyFile != "" {
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read admin_key_file: %w", err)
+		}
+		adminKey := strings.TrimRight(string(data), "\r\n")
+		if adminKey == "" {
+			return "", "", fmt.Errorf("admin_key_file %s is empty", keyFile)
+		}
+		return adminKey, "file", nil
+	}
+	if adminKey := os.Getenv(adminKeyEnv); adminKey != "" {
+		return adminKey, "env", nil
+	}
+	return "", "", fmt.Errorf("admin_key is required and must be a string")
+}
+
+// authFromParams 从请求参数中读取 admin_key 与 server_address 并构造 Auth
+func authFromParams(cfg map[string]any) (*Auth, error) {
+	adminKey, keySource, err := resolveAdminKey(cfg)
+	if err != nil {
+		return nil, err
+	}
+	serverAddress, err := singleServerAddress(cfg)
+	if err != nil {
+		return nil, err
+	}
+	requireHTTPS, _ := cfg["require_https"].(bool)
+	serverAddress, err = normalizeServerAddress(serverAddress, requireHTTPS)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := httpOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	// 只给出 host:port 时补全 Admin API 前缀，可通过 admin_prefix 覆盖默认值
+	if u, _ := url.Parse(serverAddress); u.Path == "" {
+		prefix, _ := cfg["admin_prefix"].(string)
+		if prefix == "" {
+			prefix = adminPathPrefix
+		}
+		opts = append(opts, WithAdminPrefix(prefix))
+	}
+	a := NewAuth(adminKey, serverAddress, opts...)
+	a.KeySource = keySource
+	detect := false
+	if apiVersion, ok := cfg["api_version"].(string); ok && apiVersion != "" {
+		apiVersion = strings.ToLower(apiVersion)
+		if apiVersion != "v2" && apiVersion != "v3" && apiVersion != "auto" {
+			return nil, fmt.Errorf("unsupported api_version: %s", apiVersion)
+		}
+		a.APIVersion = apiVersion
+		detect = apiVersion == "auto"
+	}
+	a.LegacySNI, _ = cfg["legacy_sni"].(bool)
+	if bodyFormat, ok := cfg["body_format"].(string); ok && bodyFormat != "" {
+		if bodyFormat != "json" && bodyFormat != "multipart" {
+			return nil, fmt.Errorf("unsupported body_format: %s", bodyFormat)
+		}
+		a.BodyFormat = bodyFormat
+	}
+	a.CompressUpload, _ = cfg["compress_upload"].(bool)
+	if a.TTLSeconds, err = ttlParam(cfg); err != nil {
+		return nil, err
+	}
+	if verbose, _ := cfg["verbose"].(bool); verbose {
+		a.timings = &timingLog{calls: make(map[string]*callTiming)}
+	}
+	if debug, _ := cfg["debug"].(bool); debug {
+		a.raw = &[]map[string]any{}
+	}
+	a.OpTimeouts, err = opTimeoutsParam(cfg)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := gatewayGroups(cfg)
+	if err != nil {
+		return nil, err
+	}
+	switch len(groups) {
+	case 0:
+	case 1:
+		a.GatewayGroup = groups[0]
+	default:
+		return nil, fmt.Errorf("multiple gateway_group values are only supported by upload_bind")
+	}
+	if mode, _ := cfg["gateway_group_mode"].(string); mode != "" {
+		if mode != "header" && mode != "path" {
+			return nil, fmt.Errorf("unsupported gateway_group_mode: %s", mode)
+		}
+		a.GatewayGroupMode = mode
+	}
+	switch v := cfg["id_path"].(type) {
+	case string:
+		if v != "" {
+			a.IDPaths = []string{v}
+		}
+	case []any:
+		for i, item := range v {
+			s, ok := item.(string)
+			if !ok || s == "" {
+				return nil, fmt.Errorf("id_path element at index %d is not a non-empty string", i)
+			}
+			a.IDPaths = append(a.IDPaths, s)
+		}
+	}
+	a.BasicUser, _ = cfg["basic_user"].(string)
+	a.BasicPass, _ = cfg["basic_pass"].(string)
+	if a.BasicUser == "" && a.BasicPass != "" {
+		return nil, fmt.Errorf("basic_user is required when basic_pass is set")
+	}
+	if scheme, _ := cfg["auth_scheme"].(string); scheme != "" {
+		scheme = strings.ToLower(scheme)
+		if scheme != "x-api-key" && scheme != "bearer" {
+			return nil, fmt.Errorf("unsupported auth_scheme: %s", scheme)
+		}
+		// 两者都使用 Authorization 请求头，无法同时携带
+		if scheme == "bearer" && a.BasicUser != "" {
+			return nil, fmt.Errorf("auth_scheme bearer cannot be combined with basic_user")
+		}
+		a.AuthScheme = scheme
+	}
+	a.ctx = runCtx
+	// api_version 为 auto 时探测网关；探测失败不中断，按默认 v3 继续，真正的请求会给出具体错误
+	if detect {
+		_, legacySet := cfg["legacy_sni"].(bool)
+		if err := a.detectAPI(!legacySet); err != nil {
+			debugf("api version detection failed, assuming %s: %v", defaultAPIVersion, err)
+		}
+	}
+	return a, nil
+}
+
+// Admin API 的默认路径前缀
+const adminPathPrefix = "/apisix/admin"
+
+// normalizeServerAddress 规范化服务地址：缺少 scheme 时补全（默认 http，
+// requireHTTPS 时为 https），去掉末尾的 /，并拒绝无法解析的地址
+func normalizeServerAddress(addr string, requireHTTPS bool) (string, error) {
+	addr = strings.TrimSpace(addr)
+	if !strings.Contains(addr, "://") {
+		if requireHTTPS {
+			addr = "https://" + addr
+		} else {
+			addr = "http://" + addr
+		}
+	}
+	u, err := url.Parse(addr)
+	if err != nil {
+		return "", fmt.Errorf("invalid server_address %q: %w", addr, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("invalid server_address %q: scheme must be http or https", addr)
+	}
+	if u.Host == "" || u.Hostname() == "" {
+		return "", fmt.Errorf("invalid server_address %q: host is missing", addr)
+	}
+	if port := u.Port(); port != "" {
+		if n, err := strconv.Atoi(port); err != nil || n <= 0 || n > 65535 {
+			return "", fmt.Errorf("invalid server_address %q: bad port %s", addr, port)
+		}
+	}
+	if requireHTTPS && u.Scheme != "https" {
+		return "", fmt.Errorf("server_address %q must use https when require_https is set", addr)
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("invalid server_address %q: query and fragment are not allowed", addr)
+	}
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = ""
+	return u.String(), nil
+}
+
+// opTimeoutsParam 解析 op_timeout_ms：数字对所有操作生效；
+// 对象按 HTTP method 分别设置（如 {"GET": 60000, "DELETE": 5000}），"*" 为默认值
+func opTimeoutsParam(cfg map[string]any) (map[string]time.Duration, error) {
+	v, ok := cfg["op_timeout_ms"]
+	if !ok || v == nil {
+		return nil, nil
+	}
+	raw := map[string]any{}
+	switch t := v.(type) {
+	case float64:
+		raw["*"] = t
+	case map[string]any:
+		raw = t
+	default:
+		return nil, fmt.Errorf("op_timeout_ms must be a number or an object keyed by HTTP method")
+	}
+	timeouts := make(map[string]time.Duration, len(raw))
+	for method, ms := range raw {
+		f, ok := ms.(float64)
+		if !ok || f <= 0 || f != math.Trunc(f) {
+			return nil, fmt.Errorf("op_timeout_ms for %s must be a positive integer", method)
+		}
+		timeouts[strings.ToUpper(method)] = time.Duration(f) * time.Millisecond
+	}
+	return timeouts, nil
+}
+
+// opContext 为单次 API 操作构造带截止时间的 context
+func (a Auth) opContext(method string) (context.Context, context.CancelFunc) {
+	ctx := a.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	d, ok := a.OpTimeouts[method]
+	if !ok {
+		d, ok = a.OpTimeouts["*"]
+	}
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// unwrapNode 兼容 v2 响应结构：单个对象包裹在 node 字段中
+func (a Auth) unwrapNode(res map[string]any) map[string]any {
+	if a.APIVersion == "v2" {
+		if node, ok := res["node"].(map[string]any); ok {
+			return node
+		}
+	}
+	return res
+}
+
+// createdID 从创建/更新响应中提取对象 id。不同版本及前置网关的响应结构不同：
+// {key, value}、{node: {key, value}}、{data: {...}}，以及 data 为数组的包裹形式；
+// 优先按 id_path 配置的路径读取，未配置或均未命中时依次尝试 key（形如 /apisix/ssls/<id>，取末段）、value.id 与 id
+func (a Auth) createdID(res map[string]any) (string, bool) {
+	for _, p := range a.IDPaths {
+		v, ok := lookupPath(res, p)
+		if !ok {
+			continue
+		}
+		if id, ok := objectID(v); ok {
+			return path.Base(id), true
+		}
+	}
+	candidates := []any{a.unwrapNode(res), res["node"], res["data"]}
+	for len(candidates) > 0 {
+		c := candidates[0]
+		candidates = candidates[1:]
+		switch v := c.(type) {
+		case []any:
+			candidates = append(candidates, v...)
+		case map[string]any:
+			if key, ok := v["key"].(string); ok && key != "" {
+				return path.Base(key), true
+			}
+			if value, ok := v["value"].(map[string]any); ok {
+				if id, ok := objectID(value["id"]); ok {
+					return id, true
+				}
+			}
+			if id, ok := objectID(v["id"]); ok {
+				return id, true
+			}
+			if data, ok := v["data"]; ok {
+				candidates = append(candidates, data)
+			}
+		}
+	}
+	return "", false
+}
+
+// lookupPath 按点分隔的路径（如 data.value.id、data.0.key）读取嵌套字段，数字段用于数组下标
+func lookupPath(v any, p string) (any, bool) {
+	for _, seg := range strings.Split(p, ".") {
+		switch node := v.(type) {
+		case map[string]any:
+			next, ok := node[seg]
+			if !ok {
+				return nil, false
+			}
+			v = next
+		case []any:
+			i, err := strconv.Atoi(seg)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			v = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// objectID 将字符串或数字形式的 id 统一为字符串
+func objectID(v any) (string, bool) {
+	switch id := v.(type) {
+	case string:
+		return id, id != ""
+	case float64:
+		return strconv.FormatFloat(id, 'f', -1, 64), true
+	}
+	return "", false
+}
+
+// parseDomains 将 domain 参数解析为字符串切片；除数组外也接受以逗号或空白分隔的字符串，
+// 便于 shell 脚本与环境变量模板直接传入 "a.com,b.com"
+func parseDomains(v any) ([]string, error) {
+	if s, ok := v.(string); ok {
+		fields := strings.FieldsFunc(s, func(r rune) bool {
+			return r == ',' || unicode.IsSpace(r)
+		})
+		list := make([]any, len(fields))
+		for i, f := range fields {
+			list[i] = f
+		}
+		v = list
+	}
+	domains, ok := v.([]interface{})
+	if !ok || len(domains) == 0 {
+		return nil, fieldErrorf("domain", "is required and must be an array or a comma-separated string")
+	}
+	domain := make([]string, len(domains))
+	var errs validationErrors
+	for i, v := range domains {
+		field := fmt.Sprintf("domain[%d]", i)
+		str, ok := v.(string)
+		if !ok {
+			errs = append(errs, fieldErrorf(field, "is not a string")...)
+			continue
+		}
+		// SNI 只能是主机名，IP 地址无法匹配 TLS 握手中的 SNI
+		if net.ParseIP(strings.Trim(str, "[]")) != nil {
+			errs = append(errs, fieldErrorf(field, "is an IP address (%s); SNI requires hostnames", str)...)
+			continue
+		}
+		sni, err := validateSNI(str)
+		if err != nil {
+			errs = append(errs, fieldErrorf(field, "%v", err)...)
+			continue
+		}
+		domain[i] = sni
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return domain, nil
+}
+
+// validateSNI 校验并规范化单个 SNI：去除空白与末尾的点并转为小写；
+// 通配符只允许出现在最左侧且独占一个标签（*.example.com），
+// foo.*.com、*foo.example.com 等 APISIX 无法匹配的写法直接拒绝
+func validateSNI(s string) (string, error) {
+	sni := strings.ToLower(strings.TrimSuffix(strings.TrimSpace(s), "."))
+	if sni == "" {
+		return "", fmt.Errorf("empty domain")
+	}
+	if len(sni) > 253 {
+		return "", fmt.Errorf("domain %q is longer than 253 characters", s)
+	}
+	labels := strings.Split(sni, ".")
+	for i, label := range labels {
+		if label == "*" {
+			if i != 0 {
+				return "", fmt.Errorf("invalid wildcard %q: '*' is only allowed as the leftmost label", s)
+			}
+			if len(labels) < 2 {
+				return "", fmt.Errorf("invalid wildcard %q: a wildcard needs a domain after it", s)
+			}
+			continue
+		}
+		if strings.Contains(label, "*") {
+			return "", fmt.Errorf("invalid wildcard %q: '*' must make up a whole label, as in *.example.com", s)
+		}
+		if label == "" || len(label) > 63 {
+			return "", fmt.Errorf("invalid domain %q: empty label or label longer than 63 characters", s)
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+				return "", fmt.Errorf("invalid domain %q: unexpected character %q (use punycode for internationalized names)", s, c)
+			}
+		}
+	}
+	return sni, nil
+}
+
+// deleteEach 逐个删除 ids，单个失败不影响其余删除；已不存在（404）视为删除成功。
+// 返回成功删除的 id、每个 id 的结果以及失败数量
+func deleteEach(a CertBackend, ids []string, onDeleted func(id string)) ([]string, []map[string]any, int) {
+	deleted := make([]string, 0, len(ids))
+	outcomes := make([]map[string]any, 0, len(ids))
+	failed := 0
+	for _, id := range ids {
+		if _, err := a.DeleteCertFromApisix(id); err != nil && !isNotFound(err) {
+			outcomes = append(outcomes, map[string]any{"cert_id": id, "status": "failed", "error": err.Error()})
+			failed++
+			continue
+		}
+		outcomes = append(outcomes, map[string]any{"cert_id": id, "status": "deleted"})
+		deleted = append(deleted, id)
+		onDeleted(id)
+	}
+	return deleted, outcomes, failed
+}
+
+// reservedExtraFields 为 extra_fields 不允许设置的字段：证书材料与对象 id 只能由插件写入；
+// desc、labels 承载托管标记与有效期，sni、snis 由 domain 决定，覆盖后插件无法再识别或匹配该证书
+var reservedExtraFields = []string{"cert", "key", "certs", "keys", "id", "desc", "labels", "sni", "snis"}
+
+// extraFieldsParam 读取 extra_fields：原样合并进 SSL 请求体的对象，
+// 用于设置插件尚未显式支持的 APISIX SSL 字段（如 ssl_protocols、type）
+func extraFieldsParam(cfg map[string]any) (map[string]any, error) {
+	v, ok := cfg["extra_fields"]
+	if !ok || v == nil {
+		return nil, nil
+	}
+	fields, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("extra_fields must be an object")
+	}
+	for _, name := range reservedExtraFields {
+		if _, ok := fields[name]; ok {
+			return nil, fieldErrorf("extra_fields."+name, "is managed by the plugin and cannot be set via extra_fields")
+		}
+	}
+	return fields, nil
+}
+
+// missingDomains 返回 names 中不在 domain 里的名称，保持 names 的顺序
+func missingDomains(domain, names []string) []string {
+	requested := make(map[string]bool, len(domain))
+	for _, d := range domain {
+		requested[d] = true
+	}
+	var missing []string
+	for _, n := range names {
+		if !requested[n] {
+			missing = append(missing, n)
+		}
+	}
+	return missing
+}
+
+// normalizeDomains 将域名统一为小写并去除首尾空白，按首次出现顺序去重
+func normalizeDomains(domain []string) []string {
+	seen := make(map[string]bool, len(domain))
+	normalized := make([]string, 0, len(domain))
+	for _, d := range domain {
+		d = strings.ToLower(strings.TrimSpace(d))
+		if d == "" || seen[d] {
+			continue
+		}
+		seen[d] = true
+		normalized = append(normalized, d)
+	}
+	return normalized
+}
+
+// stringMapParam 读取字符串 map 参数：数字、布尔等标量值转换为字符串，
+// 嵌套对象或数组返回错误；未设置时返回 nil
+func stringMapParam(cfg map[string]any, name string) (map[string]string, error) {
+	v, ok := cfg[name]
+	if !ok || v == nil {
+		return nil, nil
+	}
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, fieldErrorf(name, "must be an object")
+	}
+	out := make(map[string]string, len(m))
+	for k, item := range m {
+		switch val := item.(type) {
+		case string:
+			out[k] = val
+		case float64:
+			out[k] = strconv.FormatFloat(val, 'f', -1, 64)
+		case bool:
+			out[k] = strconv.FormatBool(val)
+		case nil:
+			out[k] = ""
+		default:
+			return nil, fieldErrorf(name+"."+k, "must be a scalar value, got %T", item)
+		}
+	}
+	return out, nil
+}
+
+// intParam 读取整数参数（JSON 数字解析为 float64），未设置时返回默认值
+func intParam(cfg map[string]any, name string, def int) (int, error) {
+	v, ok := cfg[name]
+	if !ok || v == nil {
+		return def, nil
+	}
+	f, ok := v.(float64)
+	if !ok || f != math.Trunc(f) {
+		return 0, fmt.Errorf("%s must be an integer", name)
+	}
+	return int(f), nil
+}
+
+// nonNegativeIntParam 同 intParam，但拒绝负数（如天数、时长）
+func nonNegativeIntParam(cfg map[string]any, name string, def int) (int, error) {
+	n, err := intParam(cfg, name, def)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, fieldErrorf(name, "must not be negative, got %d", n)
+	}
+	return n, nil
+}
+
+// numericValue 将响应中的数值字段（如 code、total）转换为 int，
+// 兼容 float64、json.Number、整数类型以及数字字符串
+func numericValue(v any) (int, bool) {
+	switch n := v.(type) {
+	case float64:
+		if n != math.Trunc(n) {
+			return 0, false
+		}
+		return int(n), true
+	case int:
+		return n, true
+	case int64:
+		return int(n), true
+	case json.Number:
+		i, err := n.Int64()
+		if err != nil {
+			f, err := n.Float64()
+			if err != nil || f != math.Trunc(f) {
+				return 0, false
+			}
+			return int(f), true
+		}
+		return int(i), true
+	case string:
+		s := strings.TrimSpace(n)
+		if i, err := strconv.Atoi(s); err == nil {
+			return i, true
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil && f == math.Trunc(f) {
+			return int(f), true
+		}
+	}
+	return 0, false
+}
+
+// managedMark 按 managedByField 读取 SSL 对象上的托管标记（allinssl-<sha256>），未设置时返回空字符串
+func managedMark(value map[string]any) string {
+	if managedByField == "labels" {
+		labels, _ := value["labels"].(map[string]any)
+		mark, _ := labels[managedByLabel].(string)
+		return mark
+	}
+	desc, _ := value["desc"].(string)
+	return desc
+}
+
+// isManagedCert 判断证书是否由本插件托管（托管标记以 managedPrefix 开头）
+func isManagedCert(value map[string]any) bool {
+	return strings.HasPrefix(managedMark(value), managedPrefix)
+}
+
+// storedDesc 返回写入 desc 的内容：托管标记存放在 labels 时不写 desc，保留用户备注
+func storedDesc(note string) string {
+	if managedByField == "labels" {
+		return ""
+	}
+	return note
+}
+
+// bindPlan 为一次上传绑定准备好的证书数据，解析与校验只做一次，可在多个网关间复用
+type bindPlan struct {
+	Cert    string
+	Key     string
+	SHA256  string
+	Note    string
+	Domain  []string
+	Labels  map[string]string
+	Extra   map[string]any
+	KeyType string
+	// ID 为调用方指定的证书 id，为空时由网关分配
+	ID string
+	// Allowlist 限定可以上传、合并与删除的域名
+	Allowlist domainAllowlist
+}
+
+// certIDPattern 为 APISIX 允许的对象 id 格式
+var certIDPattern = regexp.MustCompile(`^[a-zA-Z0-9._-]{1,64}$`)
+
+// certIDParam 读取并校验可选的 cert_id 参数
+func certIDParam(cfg map[string]any) (string, error) {
+	id, _ := cfg["cert_id"].(string)
+	if id != "" && !certIDPattern.MatchString(id) {
+		return "", fmt.Errorf("invalid cert_id %q: must be 1-64 characters of letters, digits, '.', '_' or '-'", id)
+	}
+	return id, nil
+}
+
+// altCertPairs 读取 certs/keys 参数：两个等长的字符串数组，逐对校验证书与私钥匹配
+func altCertPairs(cfg map[string]any) ([]string, []string, error) {
+	if cfg["certs"] == nil && cfg["keys"] == nil {
+		return nil, nil, nil
+	}
+	toStrings := func(name string) ([]string, error) {
+		list, ok := cfg[name].([]any)
+		if !ok {
+			return nil, fmt.Errorf("%s must be an array of strings", name)
+		}
+		out := make([]string, 0, len(list))
+		for i, item := range list {
+			s, ok := item.(string)
+			if !ok || s == "" {
+				return nil, fmt.Errorf("%s element at index %d is not a string", name, i)
+			}
+			out = append(out, s)
+		}
+		return out, nil
+	}
+	certs, err := toStrings("certs")
+	if err != nil {
+		return nil, nil, err
+	}
+	keys, err := toStrings("keys")
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(certs) != len(keys) {
+		return nil, nil, fmt.Errorf("certs and keys must have the same length, got %d and %d", len(certs), len(keys))
+	}
+	for i := range certs {
+		if _, err := validateKeyPair(certs[i], keys[i]); err != nil {
+			return nil, nil, fmt.Errorf("certs[%d]: %w", i, err)
+		}
+	}
+	return certs, keys, nil
+}
+
+// storeID 返回不经 Admin API 写入（etcd/standalone）时使用的 id：
+// 优先使用调用方指定的 id，否则使用证书指纹
+func (p *bindPlan) storeID() string {
+	if p.ID != "" {
+		return p.ID
+	}
+	return p.SHA256
+}
+
+// prepareBind 解析并校验证书、私钥与域名，返回绑定计划以及带有告警的初始结果
+func prepareBind(cfg map[string]any) (*bindPlan, map[string]interface{}, error) {
 	if cfg == nil {
-		return nil, fmt.Errorf("config cannot be nil")
+		return nil, nil, fmt.Errorf("config cannot be nil")
 	}
 	certStr, ok := cfg["cert"].(string)
 	if !ok || certStr == "" {
-		return nil, fmt.Errorf("cert is required and must be a string")
+		return nil, nil, fmt.Errorf("cert is required and must be a string")
 	}
 	keyStr, ok := cfg["key"].(string)
 	if !ok || keyStr == "" {
-		return nil, fmt.Errorf("key is required and must be a string")
+		return nil, nil, fmt.Errorf("key is required and must be a string")
 	}
-	adminKey, ok := cfg["admin_key"].(string)
-	if !ok || adminKey == "" {
-		return nil, fmt.Errorf("admin_key is required and must be a string")
+	if _, err := ttlParam(cfg); err != nil {
+		return nil, nil, err
 	}
-	serverAddress, ok := cfg["server_address"].(string)
-	if !ok || serverAddress == "" {
-		return nil, fmt.Errorf("server_address is required and must be a string")
+	// 可选：将证书链整理为叶子证书在前，需在校验私钥之前完成
+	if reorder, _ := cfg["reorder_chain"].(bool); reorder {
+		chain, err := parseChain(certStr)
+		if err != nil {
+			return nil, nil, err
+		}
+		chain, err = reorderChain(chain)
+		if err != nil {
+			return nil, nil, err
+		}
+		certStr = encodeChain(chain)
 	}
-	domains, ok := cfg["domain"].([]interface{})
-	if !ok || len(domains) == 0 {
-		return nil, fmt.Errorf("domain is required and must be a []interface{}")
+	keyType, err := validateKeyPair(certStr, keyStr)
+	if err != nil {
+		return nil, nil, err
 	}
-	domain := make([]string, len(domains))
-	for i, v := range domains {
-		if str, ok := v.(string); ok {
-			domain[i] = str
-		} else {
-			// 如果断言失败，可以处理错误
-			return nil, fmt.Errorf("element at index %d is not a string", i)
+	sha256, err := GetSHA256(certStr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get SHA256 of cert: %w", err)
+	}
+	note := managedPrefix + sha256
+
+	// 弱证书检查：默认只告警，strict 时直接失败（全局 strict 同样生效）
+	minRSABits, err := intParam(cfg, "min_rsa_bits", defaultMinRSABits)
+	if err != nil {
+		return nil, nil, err
+	}
+	allowSHA1, _ := cfg["allow_sha1"].(bool)
+	strict := strictMode
+	if v, ok := cfg["strict"].(bool); ok {
+		strict = v
+	}
+	leaf, err := parseLeaf(certStr)
+	if err != nil {
+		return nil, nil, err
+	}
+	// 未传入 domain 时从证书 SAN 中提取（跳过 IP SAN）
+	var domain, unrequested []string
+	if cfg["domain"] != nil {
+		domain, err = parseDomains(cfg["domain"])
+		if err != nil {
+			return nil, nil, err
+		}
+		domain = normalizeDomains(domain)
+		// 证书中未被请求的 DNS 名称：include_all_san 时自动加入，否则告警
+		unrequested = missingDomains(domain, snisFromCert(leaf))
+		if includeAll, _ := cfg["include_all_san"].(bool); includeAll && len(unrequested) > 0 {
+			domain = append(domain, unrequested...)
+			unrequested = nil
+		}
+	} else {
+		domain = snisFromCert(leaf)
+		if len(domain) == 0 {
+			return nil, nil, fmt.Errorf("domain is required: cert contains no DNS names to derive snis from")
 		}
 	}
-	sha256, err := GetSHA256(certStr)
+	allowlist, err := allowlistParam(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := allowlist.check(domain); err != nil {
+		return nil, nil, fmt.Errorf("refusing to upload: %w", err)
+	}
+	warnings := checkCertPolicy(leaf, minRSABits, allowSHA1)
+	codes := make([]string, len(warnings))
+	for i := range codes {
+		codes[i] = warnWeakCert
+	}
+	// 所有域名都不在证书中，多半是证书与目标域名配错了
+	covered := false
+	for _, d := range domain {
+		if certCoversDomain(leaf, d) {
+			covered = true
+			break
+		}
+	}
+	if !covered {
+		msg := fmt.Sprintf("none of the requested domains %v are covered by the certificate", domain)
+		if strict {
+			return nil, nil, &strictError{Code: warnDomainMismatch, Err: fmt.Errorf("domain mismatch: %s", msg)}
+		}
+		warnings = append(warnings, msg)
+		codes = append(codes, warnDomainMismatch)
+	}
+	if len(unrequested) > 0 {
+		warnings = append(warnings, fmt.Sprintf("certificate also covers %v which are not in the requested domains; set include_all_san to bind them", unrequested))
+		codes = append(codes, warnExtraSAN)
+	}
+	if strict && len(warnings) > 0 {
+		reason := "weak certificate"
+		if codes[0] != warnWeakCert {
+			reason = "certificate check failed"
+		}
+		return nil, nil, &strictError{Code: codes[0], Err: fmt.Errorf("%s: %s", reason, strings.Join(warnings, "; "))}
+	}
+	result := map[string]interface{}{"key_type": keyType, "warnings": []string{}}
+	if verify, _ := cfg["verify_chain"].(bool); verify {
+		if err := checkChain(cfg, certStr); err != nil {
+			if warnOnly, _ := cfg["chain_warn_only"].(bool); !warnOnly {
+				return nil, nil, err
+			}
+			warnings = append(warnings, err.Error())
+			codes = append(codes, warnChainInvalid)
+		}
+	}
+	// 将证书有效期写入 labels，便于通过 APISIX 标签审计过期时间；用户 labels 优先
+	labels := validityLabels(leaf)
+	userLabels, err := stringMapParam(cfg, "labels")
+	if err != nil {
+		return nil, nil, err
+	}
+	// 托管标记只能由插件写入，否则可以把任意证书伪装成托管证书
+	if _, ok := userLabels[managedByLabel]; ok {
+		return nil, nil, fieldErrorf("labels."+managedByLabel, "is reserved for the managed-by marker")
+	}
+	for k, v := range userLabels {
+		labels[k] = v
+	}
+	if managedByField == "labels" {
+		labels[managedByLabel] = note
+	}
+	extra := map[string]any{"labels": labels}
+	// 可选：由 APISIX 限定证书生效窗口（unix 时间戳），与证书自身有效期无关
+	validityStart, err := intParam(cfg, "validity_start", 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	validityEnd, err := intParam(cfg, "validity_end", 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	if validityStart < 0 || validityEnd < 0 {
+		return nil, nil, fmt.Errorf("validity_start and validity_end must be unix timestamps")
+	}
+	if validityStart > 0 && validityEnd > 0 && validityStart >= validityEnd {
+		return nil, nil, fmt.Errorf("validity_start must be before validity_end")
+	}
+	if validityStart > 0 {
+		extra["validity_start"] = validityStart
+	}
+	if validityEnd > 0 {
+		extra["validity_end"] = validityEnd
+	}
+	certID, err := certIDParam(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	// 可选：同一 SNI 上的备用证书（如 RSA 与 ECDSA 双证书），写入 certs/keys 数组
+	altCerts, altKeys, err := altCertPairs(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(altCerts) > 0 {
+		extra["certs"] = altCerts
+		extra["keys"] = altKeys
+	}
+	// idempotent 时由指纹派生固定 id，并发上传同一证书会 PUT 到同一对象而不是各自创建副本
+	if idempotent, _ := cfg["idempotent"].(bool); idempotent && certID == "" {
+		certID = managedPrefix + sha256[:32]
+	}
+	if certID != "" {
+		extra["id"] = certID
+	}
+	// extra_fields 在插件管理的字段之后合并，同名字段以用户设置为准
+	extraFields, err := extraFieldsParam(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get SHA256 of cert: %w", err)
+		return nil, nil, err
 	}
-	note := fmt.Sprintf("allinssl-%s", sha256)
+	for k, v := range extraFields {
+		extra[k] = v
+	}
+	for i, w := range warnings {
+		addWarning(result, codes[i], w)
+	}
+	return &bindPlan{
+		Cert:      certStr,
+		Key:       keyStr,
+		SHA256:    sha256,
+		Note:      note,
+		Domain:    domain,
+		Labels:    labels,
+		Extra:     extra,
+		KeyType:   keyType,
+		ID:        certID,
+		Allowlist: allowlist,
+	}, result, nil
+}
 
-	a := NewAuth(adminKey, serverAddress)
-	// 检查证书是否已存在于服务器
-	// 只根据证书名称检查是否存在，格式为 "allinssl-<sha256>"
-	certServer, err := a.listCertFromApisix()
+func Upload_bind(cfg map[string]any) (*Response, error) {
+	p, result, err := prepareBind(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list certs from Apisix: %w", err)
+		return nil, err
+	}
+
+	// Standalone 模式不调用 Admin API，直接生成 apisix.yaml 片段
+	if mode, _ := cfg["mode"].(string); mode == "standalone" {
+		checkTTLSupport(cfg, nil, result)
+		outputFile, _ := cfg["output_file"].(string)
+		return uploadStandalone(p, outputFile, result)
+	}
+	// etcd 模式绕过 Admin API，直接写入 APISIX 使用的 etcd
+	if backend, _ := cfg["backend"].(string); backend == "etcd" {
+		checkTTLSupport(cfg, nil, result)
+		return uploadEtcd(cfg, p.storeID(), p.Cert, p.Key, storedDesc(p.Note), p.Domain, p.Extra, result)
+	}
+
+	targets, kind, err := bindTargets(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if len(targets) > 0 {
+		stop := installSignalHandler()
+		defer stop()
+		return uploadMulti(cfg, p, targets, kind, result)
+	}
+
+	a, err := backendFromParams(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return bindOnBackend(a, p, cfg, result)
+}
+
+// bindOnBackend 在单个后端上执行存在性检查、上传/复用与冲突证书清理
+func bindOnBackend(a CertBackend, p *bindPlan, cfg map[string]any, result map[string]interface{}) (*Response, error) {
+	checkTTLSupport(cfg, a, result)
+	certStr, keyStr, note, domain, extra := p.Cert, p.Key, p.Note, p.Domain, p.Extra
+	desc := storedDesc(note)
+	var err error
+	// 绑定或删除成功后通知 webhook，通知失败只记录告警，不影响主流程
+	webhookURL, _ := cfg["webhook_url"].(string)
+	notify := func(action, certID string, snis []string) {
+		if webhookURL == "" {
+			return
+		}
+		err := notifyWebhook(webhookURL, map[string]any{
+			"action":      action,
+			"cert_id":     certID,
+			"snis":        snis,
+			"fingerprint": p.SHA256,
+			"status":      "success",
+		})
+		if err != nil {
+			debugf("webhook %s for cert %s failed: %v", action, certID, err)
+			addWarning(result, warnWebhookFailed, fmt.Sprintf("webhook notification failed: %v", err))
+		}
+	}
+
+	// 检查证书是否已存在于服务器
+	// 只根据证书名称检查是否存在，格式为 "allinssl-<sha256>"。
+	// force 时跳过检查直接上传，用于网关状态可疑、匹配逻辑不可信的恢复场景：
+	// 不复用已有证书，也不删除冲突证书，重复副本只在显式设置 collapse_duplicates 时合并
+	force, _ := cfg["force"].(bool)
+	var certServer []map[string]any
+	if force {
+		result["forced"] = true
+	} else {
+		certServer, err = a.listCertFromApisix()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list certs from Apisix: %w", err)
+		}
 	}
 	// certKey 为空表示未找到匹配的证书
 	var deleteCertKeyList []string = []string{}
 	deleteMap := make(map[string]bool)
+	deleteSnis := make(map[string][]string)
 	var certKey string = ""
+	// matched 为被复用的已有 SSL 对象
+	var matched map[string]any
+	// merge_snis 模式下，同一证书（desc 相同）仅合并 snis，保留原 id
+	mergeSnis, _ := cfg["merge_snis"].(bool)
+	var mergeID string
+	var mergeExisting []string
 	for _, cert := range certServer {
 		value, ok := cert["value"].(map[string]any)
 		if !ok {
 			continue
 		}
-		desc, _ := value["desc"].(string)
-		// 尝试取证书 id（可能在 value 中）
-		var id string
-		if v, ok := value["id"].(string); ok {
-			id = v
+		mark := managedMark(value)
+		// 尝试取证书 id（可能在 value 中）
+		var id string
+		if v, ok := value["id"].(string); ok {
+			id = v
+		}
+		// 尝试解析 snis
+		snis, valid := snisFromValue(value)
+
+		// rel 描述请求的 domain 相对已有 snis 的关系
+		rel := sliceRelation{Relation: relationNone}
+		if valid {
+			rel = compareSlices(domain, snis)
+		}
+		snisMatch := rel.Relation == relationEqual
+		snisPartial := rel.Relation == relationNone
+
+		// merge_snis 模式下同一证书：已覆盖全部请求域名时直接复用，否则合并
+		if mergeSnis && mark == note && id != "" && rel.Relation == relationSubset {
+			snisMatch = true
+		}
+		// 指定了 cert_id 时，其他 id 上的同一证书视为重复，按冲突删除
+		if p.ID != "" && id != p.ID {
+			snisMatch = false
 		}
-		// 尝试解析 snis
-		snisAny, _ := value["snis"].([]any)
-		snis := make([]string, 0)
-		valid := true
-		if snisAny != nil {
-			for _, v := range snisAny {
-				s, ok := v.(string)
-				if !ok {
-					valid = false
-					break
-				}
-				snis = append(snis, s)
+		if mergeSnis && mark == note && !snisMatch && id != "" && mergeID == "" && (p.ID == "" || id == p.ID) {
+			if err := p.Allowlist.check(snis); err != nil {
+				return nil, fmt.Errorf("refusing to merge into cert %s: %w", id, err)
 			}
-		} else {
-			valid = false
+			mergeID = id
+			mergeExisting = snis
+			continue
 		}
-
-		// relation: 0=none,1=partial,2=exact
-		relation := 0
-		if valid {
-			relation = compareSliceRelation(snis, domain)
+		// 指定 id 的证书会被原地覆盖，绝不能删除
+		if p.ID != "" && id == p.ID {
+			if snisMatch && mark == note {
+				certKey = id
+				matched = value
+			}
+			continue
 		}
-		snisMatch := relation == 2
-		snisPartial := relation == 0
 
 		// 如果满足条件，将 id 加入 deleteCertKeyList（去重）：
 		// 1) desc 相同但 snis 不完全一致（包括部分匹配或完全不同）
 		// 2) snis 部分匹配且 desc 不相同
-		if id != "" && ((desc == note && !snisMatch) || (!snisPartial && desc != note)) {
+		if id != "" && ((mark == note && !snisMatch) || (!snisPartial && mark != note)) {
+			// 设置了允许列表时，只删除 snis 全部在列表内且由插件托管的证书
+			if len(p.Allowlist) > 0 {
+				if err := p.Allowlist.check(snis); err != nil {
+					return nil, fmt.Errorf("refusing to delete cert %s: %w", id, err)
+				}
+				if !isManagedCert(value) {
+					return nil, fmt.Errorf("refusing to delete cert %s: it is not managed by this plugin (%s %q) and domain_allowlist is set", id, managedByField, mark)
+				}
+			}
 			if !deleteMap[id] {
 				deleteCertKeyList = append(deleteCertKeyList, id)
 				deleteMap[id] = true
+				deleteSnis[id] = snis
 			}
 		}
 
 		// 优先返回同时满足 desc==note 且 snis 匹配的证书
-		if snisMatch && desc == note {
+		if snisMatch && mark == note {
 			certKey = id
+			matched = value
 			// 继续寻找更优匹配
 			continue
 		}
 	}
+	// protect_in_use 时保留仍在服务路由的旧证书：路由 host 由旧证书覆盖、但新证书不覆盖
+	if protect, _ := cfg["protect_in_use"].(bool); protect && len(deleteCertKeyList) > 0 {
+		deleteCertKeyList, err = protectInUse(a, deleteCertKeyList, deleteSnis, domain, result)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// no_delete 时只上传并报告冲突证书，不做任何删除，便于在共享网关上试用
+	noDelete, _ := cfg["no_delete"].(bool)
+	if noDelete {
+		result["conflicting_ids"] = deleteCertKeyList
+		deleteCertKeyList = []string{}
+	}
+	// best_effort_delete 时逐个删除冲突证书，失败的记录下来继续处理其余证书，
+	// 最终返回 partial 而不是回滚整个操作
+	bestEffort, _ := cfg["best_effort_delete"].(bool)
+	restoreOnRollback, _ := cfg["restore_on_rollback"].(bool)
+	rollbackKeys, err := stringMapParam(cfg, "rollback_keys")
+	if err != nil {
+		return nil, err
+	}
+	// 删除前为冲突证书生成备份快照，回滚时据此恢复
+	var snapshot map[string]any
+	if len(deleteCertKeyList) > 0 && !bestEffort {
+		snapshot, err = rollbackBackup(certServer, deleteCertKeyList, cfg, result)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// rollbackFailure 在删除旧证书中途失败时返回 error 响应，附带回滚报告：
+	// 已删除的旧证书尽量从快照恢复，无法恢复的逐个列出
+	rollbackFailure := func(failedID string, deleteErr error, deleted []string, rollback map[string]any) (*Response, error) {
+		for k, v := range rollbackDeleted(a, snapshot, deleted, restoreOnRollback, rollbackKeys) {
+			rollback[k] = v
+		}
+		result["rollback"] = rollback
+		result["failed_delete"] = failedID
+		attachDiagnostics(a, result)
+		return &Response{
+			Status:  "error",
+			Message: fmt.Sprintf("failed to delete old cert %s: %v", failedID, deleteErr),
+			Result:  result,
+		}, nil
+	}
+	status := "success"
+	deleteBestEffort := func() {
+		deleted, outcomes, failed := deleteEach(a, deleteCertKeyList, func(id string) { notify("delete", id, nil) })
+		deleteCertKeyList = deleted
+		result["delete_results"] = outcomes
+		if failed > 0 {
+			status = "partial"
+			result["failed_deletes"] = failed
+		}
+	}
+	// 同一证书已存在但 snis 不同，合并后原地更新
+	if certKey == "" && mergeID != "" {
+		merged := normalizeDomains(append(mergeExisting, domain...))
+		certKey, err = a.updateCertToApisix(mergeID, certStr, keyStr, desc, merged, extra)
+		if err != nil {
+			return nil, fmt.Errorf("failed to merge snis into cert %s: %w", mergeID, err)
+		}
+		notify("bind", certKey, merged)
+		if bestEffort {
+			deleteBestEffort()
+		} else {
+			for i, delCertKey := range deleteCertKeyList {
+				if _, err := a.DeleteCertFromApisix(delCertKey); err != nil && !isNotFound(err) {
+					// 合并后的证书是原有对象，保留；只处理已删除的旧证书
+					return rollbackFailure(delCertKey, err, deleteCertKeyList[:i], map[string]any{"cert_id": certKey})
+				}
+				notify("delete", delCertKey, nil)
+			}
+		}
+		if len(deleteCertKeyList) > 0 {
+			result["deleted"] = deleteCertKeyList
+		}
+		result["message"] = "已合并绑定"
+		result["action"] = "merged"
+		result["changed"] = true
+		result["cert_id"] = certKey
+		result["snis"] = merged
+		result["deleted_ids"] = deleteCertKeyList
+		attachDiagnostics(a, result)
+		message := "Certificate snis merged successfully"
+		if status == "partial" {
+			message = "Certificate snis merged, but some conflicting certs could not be deleted"
+		}
+		return &Response{
+			Status:  status,
+			Message: message,
+			Result:  result,
+		}, nil
+	}
 	// 如果证书不存在，则上传证书
 	if certKey == "" {
-		certKey, err = a.uploadCertToApisix(certStr, keyStr, note, domain)
+		certKey, err = a.uploadCertToApisix(certStr, keyStr, desc, domain, extra)
 		if err != nil || certKey == "" {
 			return nil, fmt.Errorf("failed to upload to Apisix: %w", err)
 		}
-		if len(deleteCertKeyList) > 0 {
+		// verify_before_delete 时确认新证书已生效再删除旧证书；未确认则保留旧证书，避免出现无证书可用的窗口
+		if verify, _ := cfg["verify_before_delete"].(bool); verify && len(deleteCertKeyList) > 0 {
+			timeout, err := intParam(cfg, "verify_timeout_ms", int(defaultVerifyTimeout/time.Millisecond))
+			if err != nil {
+				return nil, err
+			}
+			if err := waitForCert(a, certKey, p.SHA256, time.Duration(timeout)*time.Millisecond); err != nil {
+				addWarning(result, warnUnconfirmed, fmt.Sprintf("%v; kept old certs %v", err, deleteCertKeyList))
+				result["pending_delete"] = deleteCertKeyList
+				deleteCertKeyList = []string{}
+			}
+		}
+		if len(deleteCertKeyList) > 0 && bestEffort {
+			deleteBestEffort()
+			result["deleted"] = deleteCertKeyList
+		} else if len(deleteCertKeyList) > 0 {
 			// 删除多余的证书绑定
-			for _, delCertKey := range deleteCertKeyList {
+			for i, delCertKey := range deleteCertKeyList {
 				_, err := a.DeleteCertFromApisix(delCertKey)
-				if err != nil {
-					// 记录错误但继续删除其他证书
-					fmt.Printf("Warning: failed to delete cert %s: %v\n", delCertKey, err)
-					_, err := a.DeleteCertFromApisix(certKey)
-					if err != nil {
-						fmt.Printf("Warning: failed to rollback cert %s: %v\n", certKey, err)
+				// 已被并发的调用删除时视为成功
+				if err != nil && !isNotFound(err) {
+					// 回滚：删除新上传的证书，并尽量恢复此前已删除的旧证书
+					rollback := map[string]any{"cert_id": certKey, "removed_new_cert": true}
+					if _, rbErr := a.DeleteCertFromApisix(certKey); rbErr != nil {
+						debugf("failed to rollback cert %s: %v", certKey, rbErr)
+						rollback["removed_new_cert"] = false
+						rollback["error"] = rbErr.Error()
 					}
-					return nil, fmt.Errorf("failed to delete old cert %s: %w", delCertKey, err)
+					return rollbackFailure(delCertKey, err, deleteCertKeyList[:i], rollback)
 				}
+				notify("delete", delCertKey, nil)
+			}
+			result["deleted"] = deleteCertKeyList
+		}
+		// 默认在上传后合并重复副本：POST 不是幂等的，重试或并发上传可能留下多张相同证书
+		if collapse, ok := cfg["collapse_duplicates"].(bool); (collapse || (!ok && !force)) && !noDelete {
+			kept, collapsed, err := collapseDuplicates(a, p, cfg, certKey, result)
+			if err != nil {
+				addWarning(result, warnCollapseFailed, err.Error())
+			}
+			if len(collapsed) > 0 {
+				result["collapsed"] = collapsed
 			}
+			certKey = kept
+		}
+		notify("bind", certKey, domain)
+		result["message"] = "绑定成功"
+		result["action"] = "created"
+		result["changed"] = true
+		result["cert_id"] = certKey
+		result["snis"] = domain
+		result["deleted_ids"] = deleteCertKeyList
+		attachDiagnostics(a, result)
+		message := "Certificate uploaded and bound successfully"
+		if status == "partial" {
+			message = "Certificate uploaded and bound, but some conflicting certs could not be deleted"
 		}
 		return &Response{
-			Status:  "success",
-			Message: "Certificate uploaded and bound successfully",
-			Result:  map[string]interface{}{"message": "绑定成功"},
+			Status:  status,
+			Message: message,
+			Result:  result,
 		}, nil
 	} else {
-		// 证书已存在，跳过上传步骤
+		// 证书已存在，跳过上传步骤；附带已有证书信息，并检查内容是否与 desc 中的指纹一致
+		result["message"] = "已存在绑定"
+		result["action"] = "reused"
+		result["changed"] = false
+		result["cert_id"] = certKey
+		result["snis"], _ = snisFromValue(matched)
+		result["deleted_ids"] = []string{}
+		existing := map[string]any{"cert_id": certKey}
+		existing["desc"], _ = matched["desc"].(string)
+		if managedByField == "labels" {
+			existing[managedByLabel] = managedMark(matched)
+		}
+		if stored, ok := matched["cert"].(string); ok {
+			storedSHA256, err := GetSHA256(stored)
+			existing["fingerprint_match"] = err == nil && storedSHA256 == p.SHA256
+			if err == nil {
+				existing["fingerprint"] = storedSHA256
+			}
+			if err != nil || storedSHA256 != p.SHA256 {
+				result["conflict"] = map[string]any{
+					"reason":               "content drift: stored cert does not match the fingerprint in its desc",
+					"cert_id":              certKey,
+					"expected_fingerprint": p.SHA256,
+					"stored_fingerprint":   storedSHA256,
+				}
+				addWarning(result, warnFingerprintConflict, fmt.Sprintf("cert %s has desc %s but its stored content differs; re-upload or delete it to resolve", certKey, note))
+			}
+		}
+		result["existing"] = existing
+		attachDiagnostics(a, result)
 		return &Response{
 			Status:  "success",
+			Code:    codeNoChange,
 			Message: "Certificate uploaded and bound successfully",
-			Result:  map[string]interface{}{"message": "已存在绑定"},
+			Result:  result,
 		}, nil
 	}
 }
 
-func (a Auth) uploadCertToApisix(cert, key, note string, domain []string) (string, error) {
+// sslParams 构造 SSL 对象请求体；LegacySNI 且只有一个域名时使用旧版标量 sni 字段
+func (a Auth) sslParams(cert, key, note string, domain []string, extra map[string]any) map[string]any {
 	params := map[string]any{
 		"cert": cert,
 		"key":  key,
-		"desc": note,
-		"snis": domain,
 	}
+	if note != "" {
+		params["desc"] = note
+	}
+	if a.LegacySNI && len(domain) == 1 {
+		params["sni"] = domain[0]
+	} else {
+		params["snis"] = domain
+	}
+	for k, v := range extra {
+		params[k] = v
+	}
+	return params
+}
+
+// uploadCertToApisix 创建 SSL 对象；extra 中带有 id 时使用 PUT 写入指定 id，否则 POST 由网关分配
+func (a Auth) uploadCertToApisix(cert, key, note string, domain []string, extra map[string]any) (string, error) {
+	params := a.sslParams(cert, key, note, domain, extra)
 
-	res, err := a.ApisixAPI("/ssls", params, "POST")
+	apiPath, method := "/ssls", "POST"
+	if id, _ := extra["id"].(string); id != "" {
+		apiPath, method = "/ssls/"+url.PathEscape(id), "PUT"
+	}
+	res, err := a.ApisixAPI(apiPath+a.ttlQuery(), params, method)
 	if err != nil {
 		return "", fmt.Errorf("failed to call Apisix API: %w", err)
 	}
-	certKey, ok := res["key"].(string)
+	certKey, ok := a.createdID(res)
 	if !ok {
 		return "", fmt.Errorf("invalid response format: data not found")
 	}
 	return certKey, nil
 }
 
+// updateCertToApisix 使用 PUT 覆盖指定 id 的 SSL 对象，保持 id 不变。
+// 采用读-改-写：先读取现有对象，只覆盖插件管理的字段，
+// 其他工具设置的 labels、client（mTLS）、ssl_protocols 等字段原样保留
+func (a Auth) updateCertToApisix(certKey, cert, key, note string, domain []string, extra map[string]any) (string, error) {
+	existing, err := a.getSSL(certKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to read cert %s before update: %w", certKey, err)
+	}
+	params := mergeSSL(existing, a.sslParams(cert, key, note, domain, extra))
+
+	res, err := a.ApisixAPI("/ssls/"+url.PathEscape(certKey)+a.ttlQuery(), params, "PUT")
+	if err != nil {
+		return "", fmt.Errorf("failed to call Apisix API: %w", err)
+	}
+	id, ok := a.createdID(res)
+	if !ok {
+		return "", fmt.Errorf("invalid response format: data not found")
+	}
+	return id, nil
+}
+
+// restoreSSL 以 PUT 按原 id 写回 SSL 对象快照，create_time/update_time 由网关重新生成
+func (a Auth) restoreSSL(id string, value map[string]any) error {
+	params := make(map[string]any, len(value))
+	for k, v := range value {
+		params[k] = v
+	}
+	delete(params, "create_time")
+	delete(params, "update_time")
+	if _, err := a.ApisixAPI("/ssls/"+url.PathEscape(id), params, "PUT"); err != nil {
+		return fmt.Errorf("failed to call Apisix API: %w", err)
+	}
+	return nil
+}
+
+// getSSL 读取指定 id 的 SSL 对象
+func (a Auth) getSSL(certKey string) (map[string]any, error) {
+	res, err := a.ApisixAPI("/ssls/"+url.PathEscape(certKey), map[string]interface{}{}, "GET")
+	if err != nil {
+		return nil, fmt.Errorf("failed to call Apisix API: %w", err)
+	}
+	value, ok := a.unwrapNode(res)["value"].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid response format: value not found")
+	}
+	return value, nil
+}
+
+// mergeSSL 将插件要写入的字段叠加到现有 SSL 对象上：labels 按键合并（新值优先），
+// sni/snis 整体替换，update_time 交由网关重新生成
+func mergeSSL(existing, params map[string]any) map[string]any {
+	merged := make(map[string]any, len(existing)+len(params))
+	for k, v := range existing {
+		merged[k] = v
+	}
+	delete(merged, "update_time")
+	delete(merged, "sni")
+	delete(merged, "snis")
+	for k, v := range params {
+		merged[k] = v
+	}
+	oldLabels, _ := existing["labels"].(map[string]any)
+	if len(oldLabels) > 0 {
+		labels := make(map[string]any, len(oldLabels))
+		for k, v := range oldLabels {
+			labels[k] = v
+		}
+		switch newLabels := params["labels"].(type) {
+		case map[string]string:
+			for k, v := range newLabels {
+				labels[k] = v
+			}
+		case map[string]any:
+			for k, v := range newLabels {
+				labels[k] = v
+			}
+		}
+		merged["labels"] = labels
+	}
+	return merged
+}
+
+// bindSNIs 使用 PATCH 只更新指定 SSL 对象的 snis；
+// APISIX 不会返回明文私钥，因此不能用 PUT 整体覆盖
+func (a Auth) bindSNIs(certKey string, domain []string) error {
+	params := map[string]any{"snis": domain}
+	if a.LegacySNI && len(domain) == 1 {
+		params = map[string]any{"sni": domain[0]}
+	}
+	res, err := a.ApisixAPI("/ssls/"+url.PathEscape(certKey), params, "PATCH")
+	if err != nil {
+		return fmt.Errorf("failed to call Apisix API: %w", err)
+	}
+	if _, ok := a.unwrapNode(res)["key"].(string); !ok {
+		return fmt.Errorf("invalid response format: data not found")
+	}
+	return nil
+}
+
 func (a Auth) DeleteCertFromApisix(certKey string) (bool, error) {
-	res, err := a.ApisixAPI("/ssls/"+certKey, map[string]interface{}{}, "DELETE")
+	res, err := a.ApisixAPI("/ssls/"+url.PathEscape(certKey), map[string]interface{}{}, "DELETE")
 	if err != nil {
 		return false, fmt.Errorf("failed to call Apisix API: %w", err)
 	}
-	_, ok := res["deleted"].(string)
-	if !ok {
+	// 不同版本的 APISIX 返回的 deleted 可能是字符串（"1"）或数字（1）
+	if deleted, ok := numericValue(res["deleted"]); !ok || deleted < 1 {
 		return false, fmt.Errorf("apisix api error: %s", res["message"])
 	}
-	key, ok := res["key"].(string)
+	key, ok := a.unwrapNode(res)["key"].(string)
 	if !ok {
 		return false, fmt.Errorf("invalid response format: key not found")
 	}
-	reqKey := path.Base(key)
-	if reqKey != certKey {
-		return false, fmt.Errorf("deleted key mismatch: expected %s, got %s", certKey, key)
+	// 按后缀比较，id 中含 "/" 时 path.Base 只能取到最后一段
+	if !strings.HasSuffix(key, "/ssls/"+certKey) && path.Base(key) != certKey {
+		debugf("delete %s returned unexpected key, raw response: %v", certKey, res)
+		return false, fmt.Errorf("deleted key mismatch: requested id %s, returned key %s, node %v", certKey, key, res["node"])
 	}
 	return true, nil
 
 }
 
+// 分页拉取证书列表时每页的条数（APISIX v3 允许的最大值为 500）
+const listPageSize = 500
+
+// listCertFromApisix 拉取全部证书；v3 按页拉取直到达到 total，v2 不支持分页，一次返回全部
 func (a Auth) listCertFromApisix() ([]map[string]any, error) {
-	res, err := a.ApisixAPI("/ssls", map[string]interface{}{}, "GET")
+	return a.listAll("ssls")
+}
+
+// listRoutes 拉取全部路由，分页方式与证书相同
+func (a Auth) listRoutes() ([]map[string]any, error) {
+	return a.listAll("routes")
+}
+
+// listAll 拉取 resource（如 ssls、routes）下的全部对象
+func (a Auth) listAll(resource string) ([]map[string]any, error) {
+	if a.APIVersion == "v2" {
+		items, _, err := a.listPage(resource, 0, 0)
+		return items, err
+	}
+	items := make([]map[string]any, 0)
+	for page := 1; ; page++ {
+		list, total, err := a.listPage(resource, page, listPageSize)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, list...)
+		// 未返回 total 的网关视为不支持分页
+		if total < 0 || len(items) >= total || len(list) < listPageSize {
+			return items, nil
+		}
+	}
+}
+
+// listCertPage 拉取单页证书，page 为 0 时不带分页参数；total 未知时返回 -1
+func (a Auth) listCertPage(page, pageSize int) ([]map[string]any, int, error) {
+	return a.listPage("ssls", page, pageSize)
+}
+
+// listPage 拉取 resource 的单页对象，page 为 0 时不带分页参数；total 未知时返回 -1
+func (a Auth) listPage(resource string, page, pageSize int) ([]map[string]any, int, error) {
+	apiPath := "/" + resource
+	if page > 0 {
+		apiPath = fmt.Sprintf("/%s?page=%d&page_size=%d", resource, page, pageSize)
+	}
+	res, err := a.ApisixAPI(apiPath, map[string]interface{}{}, "GET")
 	if err != nil {
-		return nil, fmt.Errorf("failed to call Apisix API: %w", err)
+		return nil, 0, fmt.Errorf("failed to call Apisix API: %w", err)
 	}
+	// v3: {"list": [...], "total": n}；v2: {"node": {"nodes": [...]}}
 	list, ok := res["list"].([]any)
+	if a.APIVersion == "v2" {
+		node, _ := res["node"].(map[string]any)
+		list, ok = node["nodes"].([]any)
+	}
 	if !ok {
-		return nil, fmt.Errorf("invalid response format: data not found")
+		return nil, 0, fmt.Errorf("invalid response format: data not found")
 	}
 	certs := make([]map[string]any, 0, len(list))
 	for _, cert := range list {
 		certMap, ok := cert.(map[string]any)
 		if !ok {
-			return nil, fmt.Errorf("invalid response format: cert item is not a map")
+			return nil, 0, fmt.Errorf("invalid response format: cert item is not a map")
 		}
 		certs = append(certs, certMap)
 	}
-	return certs, nil
+	total := -1
+	if t, ok := numericValue(res["total"]); ok {
+		total = t
+	}
+	return certs, total, nil
 }
 
-// 比较两个字符串切片是否包含相同元素（顺序不敏感）
-// compareSliceRelation compares two string slices and returns:
-// 0 => no overlap, 1 => partial overlap (some common elements, but not identical), 2 => exactly identical (same elements and counts)
-func compareSliceRelation(a, b []string) int {
-	if len(a) == 0 || len(b) == 0 {
-		return 0
+// snisFromValue 解析 SSL 对象中的 snis 字段（兼容旧版标量 sni 字段），
+// 字段缺失或包含非字符串元素时 ok 为 false
+func snisFromValue(value map[string]any) ([]string, bool) {
+	snisAny, _ := value["snis"].([]any)
+	if snisAny == nil {
+		if sni, ok := value["sni"].(string); ok && sni != "" {
+			return []string{sni}, true
+		}
+		return []string{}, false
 	}
-	// count elements of a
-	cnt := make(map[string]int)
-	for _, s := range a {
-		cnt[s]++
+	snis := make([]string, 0, len(snisAny))
+	for _, v := range snisAny {
+		s, ok := v.(string)
+		if !ok {
+			return snis, false
+		}
+		snis = append(snis, s)
 	}
-	overlap := 0
-	// track counts for exact comparison
-	cntCopy := make(map[string]int)
-	for k, v := range cnt {
-		cntCopy[k] = v
+	return snis, true
+}
+
+// 两个域名集合之间的关系
+const (
+	relationNone     = "none"     // 没有交集
+	relationPartial  = "partial"  // 有交集，但互不包含
+	relationSubset   = "subset"   // A 是 B 的真子集
+	relationSuperset = "superset" // A 是 B 的真超集
+	relationEqual    = "equal"    // 元素完全相同
+)
+
+// sliceRelation 描述两个字符串集合的比较结果
+type sliceRelation struct {
+	Overlap  int      // 共同元素个数
+	OnlyInA  []string // 只在 A 中出现的元素
+	OnlyInB  []string // 只在 B 中出现的元素
+	Relation string   // relationNone / relationPartial / relationSubset / relationSuperset / relationEqual
+}
+
+// compareSlices 按集合语义比较 a 与 b（顺序与重复元素不敏感）。
+// 通配符按字面比较，*.example.com 与 www.example.com 视为不同元素
+func compareSlices(a, b []string) sliceRelation {
+	setA := make(map[string]bool, len(a))
+	for _, s := range a {
+		setA[s] = true
 	}
+	setB := make(map[string]bool, len(b))
 	for _, s := range b {
-		if cnt[s] > 0 {
-			overlap++
-			cnt[s]--
-		}
-	}
-	// check exact: lengths equal and all counts in cnt are zero after matching
-	exact := false
-	if len(a) == len(b) {
-		allZero := true
-		for _, v := range cnt {
-			if v != 0 {
-				allZero = false
-				break
-			}
+		setB[s] = true
+	}
+	r := sliceRelation{OnlyInA: []string{}, OnlyInB: []string{}}
+	seen := make(map[string]bool, len(a))
+	for _, s := range a {
+		if seen[s] {
+			continue
+		}
+		seen[s] = true
+		if setB[s] {
+			r.Overlap++
+		} else {
+			r.OnlyInA = append(r.OnlyInA, s)
 		}
-		if allZero {
-			exact = true
+	}
+	seen = make(map[string]bool, len(b))
+	for _, s := range b {
+		if !seen[s] && !setA[s] {
+			r.OnlyInB = append(r.OnlyInB, s)
 		}
+		seen[s] = true
 	}
-	if exact {
-		return 2
+	switch {
+	case r.Overlap == 0:
+		r.Relation = relationNone
+	case len(r.OnlyInA) == 0 && len(r.OnlyInB) == 0:
+		r.Relation = relationEqual
+	case len(r.OnlyInA) == 0:
+		r.Relation = relationSubset
+	case len(r.OnlyInB) == 0:
+		r.Relation = relationSuperset
+	default:
+		r.Relation = relationPartial
 	}
-	if overlap > 0 {
+	return r
+}
+
+// 比较两个字符串切片是否包含相同元素（顺序不敏感）
+// compareSliceRelation compares two string slices and returns:
+// 0 => no overlap, 1 => partial overlap (some common elements, but not identical), 2 => exactly identical (same elements)
+func compareSliceRelation(a, b []string) int {
+	switch compareSlices(a, b).Relation {
+	case relationNone:
+		return 0
+	case relationEqual:
+		return 2
+	default:
 		return 1
 	}
-	// no overlap
-	return 0
 }
 
 // ApisixAPI 支持 GET/DELETE/POST/PUT，所有非 GET/DELETE 请求使用 JSON；不再计算或发送签名。
 // 约定：GET/DELETE 不包含参数；其他方法通过 JSON body 发送 `data`。
+// 遇到 429 限流时按 Retry-After 等待后重试，最多 MaxRetries 次。
 func (a Auth) ApisixAPI(apiPath string, data map[string]interface{}, method string) (map[string]interface{}, error) {
-	AdminKey := a.AdminKey
 	// 根据 method 构造请求（调用方必须传入有效 method）
 	method = strings.ToUpper(method)
-	var req *http.Request
-	var err error
-	urlStr := a.ServerAddress + apiPath
-	if method == "GET" || method == "DELETE" {
-		// GET/DELETE 不带参数，直接请求路径
-		req, err = http.NewRequest(method, urlStr, nil)
-		if err != nil {
-			return nil, err
+	urlStr := a.ServerAddress + a.AdminPrefix + apiPath
+	if a.GatewayGroup != "" && a.GatewayGroupMode == "path" {
+		urlStr = a.ServerAddress + a.AdminPrefix + "/gateway_groups/" + url.PathEscape(a.GatewayGroup) + apiPath
+	}
+	var body []byte
+	contentType := "application/json"
+	if method != "GET" && method != "DELETE" {
+		var err error
+		if a.BodyFormat == "multipart" {
+			body, contentType, err = encodeMultipart(data)
+		} else {
+			body, err = json.Marshal(data)
 		}
-	} else {
-		_body, err := json.Marshal(data)
 		if err != nil {
 			return nil, err
 		}
-		req, err = http.NewRequest(method, urlStr, strings.NewReader(string(_body)))
+	}
+	contentEncoding := ""
+	if a.CompressUpload && len(body) >= compressMinSize {
+		compressed, err := gzipBody(body)
 		if err != nil {
 			return nil, err
 		}
-		req.Header.Add("Content-Type", "application/json")
+		body, contentEncoding = compressed, "gzip"
 	}
 
-	// 公共请求头（不包含签名）
-	req.Header.Add("X-API-KEY", AdminKey)
+	if a.timings != nil {
+		start := time.Now()
+		defer func() {
+			a.timings.add(method+" "+apiPath, float64(time.Since(start).Microseconds())/1000)
+		}()
+	}
 
-	client := http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
+	ctx, cancel := a.opContext(method)
+	defer cancel()
+	// 每次调用开始时清除上一次调用的重试记录，避免后续调用的结果附带过期的 retry_info
+	if a.retry != nil {
+		a.retry.last = nil
 	}
-	defer resp.Body.Close()
-	r, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+	// retried 记录每次被重试的失败尝试；发生过重试时，最终结果记入 retry_info
+	var retried []string
+	done := func(err error) error {
+		if len(retried) == 0 {
+			return err
+		}
+		info := retryInfo{Operation: method + " " + apiPath, Attempts: len(retried) + 1, Errors: retried}
+		if err != nil {
+			info.Errors = append(info.Errors, err.Error())
+		}
+		if a.retry != nil {
+			a.retry.last = &info
+		}
+		if err == nil {
+			return nil
+		}
+		return &retryError{Info: info, Err: err}
+	}
+	var resp *http.Response
+	var r []byte
+	var err error
+	for attempt := 0; ; attempt++ {
+		release, err := acquireSlot(ctx, method)
+		if err != nil {
+			return nil, done(fmt.Errorf("%s %s: %w", method, apiPath, err))
+		}
+		resp, r, err = a.send(ctx, method, urlStr, body, contentType, contentEncoding)
+		release()
+		if err != nil {
+			return nil, done(err)
+		}
+		if resp.StatusCode != http.StatusTooManyRequests || attempt >= a.MaxRetries {
+			break
+		}
+		wait := retryAfter(resp.Header.Get("Retry-After"), time.Now())
+		debugf("%s %s rate limited, retrying in %s (attempt %d/%d)", method, apiPath, wait, attempt+1, a.MaxRetries)
+		retried = append(retried, fmt.Sprintf("HTTP %d, retry after %s", resp.StatusCode, wait))
+		select {
+		case <-ctx.Done():
+			return nil, done(fmt.Errorf("%s %s aborted while waiting to retry: %w", method, apiPath, ctx.Err()))
+		case <-time.After(wait):
+		}
+	}
+	var result map[string]interface{}
+	err = json.Unmarshal(r, &result)
+	if a.raw != nil {
+		entry := map[string]any{"method": method, "path": apiPath, "http_status": resp.StatusCode}
+		if err == nil {
+			entry["response"] = redactSecrets(result)
+		} else {
+			entry["response"] = string(r)
+		}
+		*a.raw = append(*a.raw, entry)
+	}
+	if hint := dataPlaneHint(resp, r, err == nil, result); hint != "" {
+		return nil, done(fmt.Errorf("apisix returned HTTP %d from %s, %s", resp.StatusCode, urlStr, hint))
 	}
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		bodyPreview := string(r)
 		if len(bodyPreview) > 500 {
 			bodyPreview = bodyPreview[:500] + "..."
 		}
-		return nil, fmt.Errorf("apisix returned HTTP %d: %s", resp.StatusCode, bodyPreview)
+		return nil, done(newAPIError(resp.StatusCode, bodyPreview, result))
 	}
-	var result map[string]interface{}
-	err = json.Unmarshal(r, &result)
 	if err != nil {
 		bodyPreview := string(r)
 		if len(bodyPreview) > 500 {
 			bodyPreview = bodyPreview[:500] + "..."
 		}
-		return nil, fmt.Errorf("apisix response is not valid JSON: %w, response: %s", err, bodyPreview)
+		return nil, done(fmt.Errorf("apisix response is not valid JSON: %w, response: %s", err, bodyPreview))
+	}
+	return result, done(nil)
+}
+
+// apiError 为 Admin API 返回非 2xx 状态码时的错误；APISIXCode/APISIXMessage 取自响应体中的
+// code 与 error_msg（或 message），响应体不是 JSON 或不含这些字段时为空
+type apiError struct {
+	StatusCode    int
+	Body          string
+	APISIXCode    string
+	APISIXMessage string
+}
+
+// newAPIError 根据状态码与响应体构造 apiError，result 为解析后的响应体（不是 JSON 时为 nil）
+func newAPIError(statusCode int, body string, result map[string]any) *apiError {
+	e := &apiError{StatusCode: statusCode, Body: body}
+	if msg, ok := result["error_msg"].(string); ok {
+		e.APISIXMessage = msg
+	} else if msg, ok := result["message"].(string); ok {
+		e.APISIXMessage = msg
+	}
+	// code 可能是数字（含 json.Number）或字符串，数字统一格式化为整数
+	if code, ok := numericValue(result["code"]); ok {
+		e.APISIXCode = strconv.Itoa(code)
+	} else if code, ok := result["code"].(string); ok {
+		e.APISIXCode = code
+	}
+	return e
+}
+
+// details 返回附加到错误响应 Result 中的字段
+func (e *apiError) details() map[string]any {
+	d := map[string]any{"http_status": e.StatusCode}
+	if e.APISIXCode != "" {
+		d["apisix_code"] = e.APISIXCode
+	}
+	if e.APISIXMessage != "" {
+		d["apisix_message"] = e.APISIXMessage
+	}
+	return d
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("apisix returned HTTP %d: %s", e.StatusCode, e.Body)
+}
+
+// isNotFound 判断错误是否为 Admin API 返回的 404
+func isNotFound(err error) bool {
+	var e *apiError
+	return errors.As(err, &e) && e.StatusCode == http.StatusNotFound
+}
+
+// dataPlaneHint 识别 server_address 误指向数据面（默认 9080 端口）的情况：
+// 数据面对未匹配路由返回 {"error_msg":"404 Route Not Found"}，或返回 HTML 页面，
+// 而 Admin API 总是返回 JSON。识别到时返回给用户的修正建议，否则返回空字符串
+func dataPlaneHint(resp *http.Response, body []byte, isJSON bool, result map[string]any) string {
+	const hint = "server_address looks like the APISIX data plane or another web server rather than the Admin API; " +
+		"point it at the Admin API endpoint including its prefix, e.g. http://127.0.0.1:9180/apisix/admin"
+	contentType := resp.Header.Get("Content-Type")
+	if strings.Contains(contentType, "text/html") || bytes.HasPrefix(bytes.TrimSpace(body), []byte("<")) {
+		return hint
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		if !isJSON {
+			return hint
+		}
+		if msg, _ := result["error_msg"].(string); msg == "404 Route Not Found" {
+			return hint
+		}
+	}
+	return ""
+}
+
+// send 发送单次请求并读取完整响应体；body 为 nil 时不携带请求体，contentEncoding 非空时设置 Content-Encoding
+func (a Auth) send(ctx context.Context, method, urlStr string, body []byte, contentType, contentEncoding string) (*http.Response, []byte, error) {
+	var req *http.Request
+	var err error
+	if body == nil {
+		// GET/DELETE 不带参数，直接请求路径
+		req, err = http.NewRequestWithContext(ctx, method, urlStr, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else {
+		req, err = http.NewRequestWithContext(ctx, method, urlStr, bytes.NewReader(body))
+		if err != nil {
+			return nil, nil, err
+		}
+		req.Header.Add("Content-Type", contentType)
+		if contentEncoding != "" {
+			req.Header.Add("Content-Encoding", contentEncoding)
+		}
+	}
+
+	// 公共请求头（不包含签名）
+	if a.AuthScheme == "bearer" {
+		req.Header.Add("Authorization", "Bearer "+a.AdminKey)
+	} else {
+		req.Header.Add("X-API-KEY", a.AdminKey)
+	}
+	if a.APIVersion != "" {
+		req.Header.Add("X-API-VERSION", a.APIVersion)
+	}
+	if a.BasicUser != "" {
+		req.SetBasicAuth(a.BasicUser, a.BasicPass)
+	}
+	if a.GatewayGroup != "" && a.GatewayGroupMode != "path" {
+		req.Header.Add("X-Gateway-Group", a.GatewayGroup)
+	}
+
+	resp, err := a.client().Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	r, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, r, nil
+}
+
+// encodeMultipart 将请求数据编码为 multipart/form-data：字符串字段原样写入，
+// 其他类型（如 snis、labels）以 JSON 编码后写入，字段按名称排序
+func encodeMultipart(data map[string]interface{}) ([]byte, string, error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	names := make([]string, 0, len(data))
+	for name := range data {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value, ok := data[name].(string)
+		if !ok {
+			encoded, err := json.Marshal(data[name])
+			if err != nil {
+				return nil, "", err
+			}
+			value = string(encoded)
+		}
+		if err := w.WriteField(name, value); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), w.FormDataContentType(), nil
+}
+
+// 429 未携带 Retry-After 时的默认等待时间，以及允许等待的上限
+const (
+	defaultRetryAfter = time.Second
+	maxRetryAfter     = 60 * time.Second
+)
+
+// retryAfter 解析 Retry-After 头（秒数或 HTTP-date），结果限制在 [0, maxRetryAfter]
+func retryAfter(header string, now time.Time) time.Duration {
+	header = strings.TrimSpace(header)
+	wait := defaultRetryAfter
+	if header != "" {
+		if secs, err := strconv.Atoi(header); err == nil {
+			wait = time.Duration(secs) * time.Second
+		} else if t, err := http.ParseTime(header); err == nil {
+			wait = t.Sub(now)
+		}
+	}
+	if wait < 0 {
+		wait = 0
+	}
+	if wait > maxRetryAfter {
+		wait = maxRetryAfter
 	}
-	return result, nil
+	return wait
 }
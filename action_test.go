@@ -0,0 +1,644 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExtraFieldsParam(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       map[string]any
+		wantField string
+	}{
+		{"absent", map[string]any{}, ""},
+		{"allowed", map[string]any{"extra_fields": map[string]any{"ssl_protocols": []any{"TLSv1.3"}, "type": "server"}}, ""},
+		{"cert", map[string]any{"extra_fields": map[string]any{"cert": "x"}}, "extra_fields.cert"},
+		{"id", map[string]any{"extra_fields": map[string]any{"id": "1"}}, "extra_fields.id"},
+		{"desc", map[string]any{"extra_fields": map[string]any{"desc": "mine"}}, "extra_fields.desc"},
+		{"labels", map[string]any{"extra_fields": map[string]any{"labels": map[string]any{}}}, "extra_fields.labels"},
+		{"sni", map[string]any{"extra_fields": map[string]any{"sni": "a.com"}}, "extra_fields.sni"},
+		{"snis", map[string]any{"extra_fields": map[string]any{"snis": []any{"a.com"}}}, "extra_fields.snis"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := extraFieldsParam(tt.cfg)
+			if tt.wantField == "" {
+				if err != nil {
+					t.Fatalf("extraFieldsParam() error = %v", err)
+				}
+				return
+			}
+			var ve validationErrors
+			if !errors.As(err, &ve) || ve[0].Field != tt.wantField {
+				t.Fatalf("extraFieldsParam() error = %v, want field error on %s", err, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestExtraFieldsParamNotObject(t *testing.T) {
+	if _, err := extraFieldsParam(map[string]any{"extra_fields": "x"}); err == nil {
+		t.Fatal("extraFieldsParam() accepted a string")
+	}
+}
+
+func TestSSLPathEscaping(t *testing.T) {
+	stub := newAdminStub(t)
+	certPEM, keyPEM := testCert(t, "a.test")
+	a := NewAuth("test-key", stub.URL)
+	tests := []struct {
+		id      string
+		escaped string
+	}{
+		{"plain-1", "plain-1"},
+		{"a/b", "a%2Fb"},
+		{"a b?c", "a%20b%3Fc"},
+		{"50%#x", "50%25%23x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			stub.putSSL(tt.id, map[string]any{"cert": certPEM, "key": keyPEM, "snis": []any{"a.test"}})
+			if _, err := a.getSSL(tt.id); err != nil {
+				t.Fatalf("getSSL(%q) error = %v", tt.id, err)
+			}
+			if err := a.bindSNIs(tt.id, []string{"a.test", "b.test"}); err != nil {
+				t.Fatalf("bindSNIs(%q) error = %v", tt.id, err)
+			}
+			if _, err := a.updateCertToApisix(tt.id, certPEM, keyPEM, "allinssl-x", []string{"a.test"}, nil); err != nil {
+				t.Fatalf("updateCertToApisix(%q) error = %v", tt.id, err)
+			}
+			if ok, err := a.DeleteCertFromApisix(tt.id); err != nil || !ok {
+				t.Fatalf("DeleteCertFromApisix(%q) = %v, %v", tt.id, ok, err)
+			}
+			for _, method := range []string{"GET", "PATCH", "PUT", "DELETE"} {
+				if !stub.requested(method + " /ssls/" + tt.escaped) {
+					t.Errorf("no %s request to /ssls/%s", method, tt.escaped)
+				}
+			}
+			if ids := stub.sslIDs(); len(ids) != 0 {
+				t.Errorf("objects left after delete: %v", ids)
+			}
+		})
+	}
+}
+
+func TestParseDomains(t *testing.T) {
+	tests := []struct {
+		name      string
+		in        any
+		want      []string
+		wantField string
+	}{
+		{"array", []any{"A.test", "*.b.test."}, []string{"a.test", "*.b.test"}, ""},
+		{"comma string", "a.test, b.test", []string{"a.test", "b.test"}, ""},
+		{"ipv4", []any{"a.test", "10.0.0.1"}, nil, "domain[1]"},
+		{"ipv6", []any{"::1"}, nil, "domain[0]"},
+		{"bracketed ipv6", "[2001:db8::1]", nil, "domain[0]"},
+		{"not a string", []any{1}, nil, "domain[0]"},
+		{"empty", []any{}, nil, "domain"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDomains(tt.in)
+			if tt.wantField != "" {
+				var ve validationErrors
+				if !errors.As(err, &ve) || ve[0].Field != tt.wantField {
+					t.Fatalf("parseDomains() error = %v, want field error on %s", err, tt.wantField)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseDomains() error = %v", err)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("parseDomains() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSSLParamsSNIField(t *testing.T) {
+	tests := []struct {
+		name     string
+		legacy   bool
+		domain   []string
+		wantSNI  any
+		wantSNIs any
+	}{
+		{"default single", false, []string{"a.test"}, nil, []string{"a.test"}},
+		{"legacy single", true, []string{"a.test"}, "a.test", nil},
+		{"legacy multiple", true, []string{"a.test", "b.test"}, nil, []string{"a.test", "b.test"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := Auth{LegacySNI: tt.legacy}
+			params := a.sslParams("cert", "key", "", tt.domain, nil)
+			if got := params["sni"]; got != tt.wantSNI {
+				t.Errorf("sni = %v, want %v", got, tt.wantSNI)
+			}
+			gotSNIs, _ := params["snis"].([]string)
+			wantSNIs, _ := tt.wantSNIs.([]string)
+			if strings.Join(gotSNIs, ",") != strings.Join(wantSNIs, ",") {
+				t.Errorf("snis = %v, want %v", params["snis"], tt.wantSNIs)
+			}
+			if _, ok := params["desc"]; ok {
+				t.Error("desc set for an empty note")
+			}
+		})
+	}
+}
+
+func TestSnisFromValue(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  map[string]any
+		want   []string
+		wantOK bool
+	}{
+		{"snis", map[string]any{"snis": []any{"a.test", "b.test"}}, []string{"a.test", "b.test"}, true},
+		{"sni", map[string]any{"sni": "a.test"}, []string{"a.test"}, true},
+		{"snis wins", map[string]any{"snis": []any{"b.test"}, "sni": "a.test"}, []string{"b.test"}, true},
+		{"empty sni", map[string]any{"sni": ""}, []string{}, false},
+		{"missing", map[string]any{}, []string{}, false},
+		{"non-string element", map[string]any{"snis": []any{"a.test", 1}}, []string{"a.test"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := snisFromValue(tt.value)
+			if ok != tt.wantOK || strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("snisFromValue() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+// TestUploadBindLegacySNI 以标量 sni 上传，再次上传同一证书时通过 sni 字段识别并复用
+func TestUploadBindLegacySNI(t *testing.T) {
+	stub := newAdminStub(t)
+	certPEM, keyPEM := testCert(t, "a.test")
+	params := stub.params(map[string]any{"cert": certPEM, "key": keyPEM, "legacy_sni": true})
+	resp := runAction(t, "upload_bind", params)
+	if resp.Status != "success" {
+		t.Fatalf("upload_bind: %s", resp.Message)
+	}
+	id, _ := resp.Result["cert_id"].(string)
+	stub.mu.Lock()
+	stored := stub.ssls[id]
+	stub.mu.Unlock()
+	if stored["sni"] != "a.test" || stored["snis"] != nil {
+		t.Fatalf("stored sni = %v, snis = %v, want scalar sni only", stored["sni"], stored["snis"])
+	}
+
+	resp = runAction(t, "upload_bind", params)
+	if resp.Status != "success" || resp.Result["cert_id"] != id || resp.Result["changed"] != false {
+		t.Errorf("re-upload = %s %v, want reuse of %s", resp.Message, resp.Result, id)
+	}
+	if ids := stub.sslIDs(); len(ids) != 1 {
+		t.Errorf("ssl objects = %v, want only %s", ids, id)
+	}
+}
+
+// TestSingleElementTargets 只有一个元素的 server_address 与 gateway_group 数组按单个目标处理
+func TestSingleElementTargets(t *testing.T) {
+	stub := newAdminStub(t)
+	certPEM, keyPEM := testCert(t, "a.test")
+	single := map[string]any{"server_address": []any{stub.URL}, "gateway_group": []any{"g1"}}
+	upload := stub.params(single)
+	upload["cert"], upload["key"] = certPEM, keyPEM
+	resp := runAction(t, "upload_bind", upload)
+	if resp.Status != "success" || resp.Result["cert_id"] == nil {
+		t.Fatalf("upload_bind = %s %v", resp.Message, resp.Result)
+	}
+	resp = runAction(t, "list_certs", stub.params(single))
+	if resp.Status != "success" || resp.Result["count"] != float64(1) {
+		t.Errorf("list_certs = %s %v", resp.Message, resp.Result)
+	}
+	resp = runAction(t, "list_certs", stub.params(map[string]any{"server_address": []any{stub.URL, stub.URL}}))
+	if resp.Status != "error" || !strings.Contains(resp.Message, "only supported by upload_bind") {
+		t.Errorf("list_certs with two servers = %s %s", resp.Status, resp.Message)
+	}
+}
+
+func TestCompareSlices(t *testing.T) {
+	tests := []struct {
+		name        string
+		a, b        []string
+		want        string
+		wantOverlap int
+		wantOnlyA   []string
+		wantOnlyB   []string
+	}{
+		{"both empty", nil, nil, relationNone, 0, nil, nil},
+		{"disjoint", []string{"a"}, []string{"b"}, relationNone, 0, []string{"a"}, []string{"b"}},
+		{"equal", []string{"a", "b"}, []string{"b", "a"}, relationEqual, 2, nil, nil},
+		{"equal with duplicates", []string{"a", "a", "b"}, []string{"b", "a", "b"}, relationEqual, 2, nil, nil},
+		{"subset", []string{"a"}, []string{"a", "b"}, relationSubset, 1, nil, []string{"b"}},
+		{"subset with duplicates", []string{"a", "a"}, []string{"a", "b", "b"}, relationSubset, 1, nil, []string{"b"}},
+		{"superset", []string{"a", "b", "c"}, []string{"c"}, relationSuperset, 1, []string{"a", "b"}, nil},
+		{"partial", []string{"a", "b"}, []string{"b", "c"}, relationPartial, 1, []string{"a"}, []string{"c"}},
+		{"wildcard is literal", []string{"*.a.test"}, []string{"www.a.test"}, relationNone, 0, []string{"*.a.test"}, []string{"www.a.test"}},
+		{"wildcard equal", []string{"*.a.test", "a.test"}, []string{"a.test", "*.a.test"}, relationEqual, 2, nil, nil},
+		{"empty a", nil, []string{"a"}, relationNone, 0, nil, []string{"a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := compareSlices(tt.a, tt.b)
+			if got.Relation != tt.want || got.Overlap != tt.wantOverlap ||
+				strings.Join(got.OnlyInA, ",") != strings.Join(tt.wantOnlyA, ",") ||
+				strings.Join(got.OnlyInB, ",") != strings.Join(tt.wantOnlyB, ",") {
+				t.Errorf("compareSlices(%v, %v) = %+v, want %s overlap=%d onlyA=%v onlyB=%v",
+					tt.a, tt.b, got, tt.want, tt.wantOverlap, tt.wantOnlyA, tt.wantOnlyB)
+			}
+			if got.OnlyInA == nil || got.OnlyInB == nil {
+				t.Error("OnlyInA/OnlyInB must be non-nil")
+			}
+		})
+	}
+}
+
+func TestCompareSliceRelation(t *testing.T) {
+	tests := []struct {
+		a, b []string
+		want int
+	}{
+		{[]string{"a"}, []string{"b"}, 0},
+		{[]string{"a"}, []string{"a", "b"}, 1},
+		{[]string{"a", "b"}, []string{"b"}, 1},
+		{[]string{"a", "b"}, []string{"b", "c"}, 1},
+		{[]string{"a", "b", "a"}, []string{"b", "a"}, 2},
+	}
+	for _, tt := range tests {
+		if got := compareSliceRelation(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareSliceRelation(%v, %v) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+// TestUploadBindSNIRelation 已有同一证书绑定 a.test 与 www.a.test 时，按请求域名与已有 snis 的关系决定复用、合并或替换
+func TestUploadBindSNIRelation(t *testing.T) {
+	certPEM, keyPEM := testCert(t, "a.test", "www.a.test", "b.test")
+	sha, err := GetSHA256(certPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		domain     []any
+		merge      bool
+		wantAction string
+		wantID     string
+		wantSNIs   string
+		wantIDs    int
+	}{
+		{"subset reused with merge", []any{"a.test"}, true, "reused", "1", "a.test,www.a.test", 1},
+		{"equal reused", []any{"www.a.test", "a.test"}, true, "reused", "1", "a.test,www.a.test", 1},
+		{"partial merged", []any{"a.test", "b.test"}, true, "merged", "1", "a.test,www.a.test,b.test", 1},
+		{"superset merged", []any{"a.test", "www.a.test", "b.test"}, true, "merged", "1", "a.test,www.a.test,b.test", 1},
+		{"subset replaced without merge", []any{"a.test"}, false, "created", "", "a.test", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newAdminStub(t)
+			stub.putSSL("1", map[string]any{"cert": certPEM, "key": keyPEM, "snis": []any{"a.test", "www.a.test"}, "desc": managedPrefix + sha})
+			resp := runAction(t, "upload_bind", stub.params(map[string]any{
+				"cert": certPEM, "key": keyPEM, "domain": tt.domain, "merge_snis": tt.merge,
+			}))
+			if resp.Status != "success" {
+				t.Fatalf("upload_bind: %s", resp.Message)
+			}
+			if resp.Result["action"] != tt.wantAction {
+				t.Errorf("action = %v, want %s", resp.Result["action"], tt.wantAction)
+			}
+			id, _ := resp.Result["cert_id"].(string)
+			if tt.wantID != "" && id != tt.wantID {
+				t.Errorf("cert_id = %s, want %s", id, tt.wantID)
+			}
+			stub.mu.Lock()
+			snis, _ := snisFromValue(stub.ssls[id])
+			stub.mu.Unlock()
+			if strings.Join(snis, ",") != tt.wantSNIs {
+				t.Errorf("stored snis = %v, want %s", snis, tt.wantSNIs)
+			}
+			if ids := stub.sslIDs(); len(ids) != tt.wantIDs {
+				t.Errorf("ssl objects = %v, want %d", ids, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestMergeSSL(t *testing.T) {
+	existing := map[string]any{
+		"id":            "1",
+		"cert":          "old-cert",
+		"sni":           "old.test",
+		"labels":        map[string]any{"team": "edge", "not-after": "2020-01-01"},
+		"client":        map[string]any{"ca": "CA"},
+		"ssl_protocols": []any{"TLSv1.3"},
+		"create_time":   float64(1),
+		"update_time":   float64(2),
+	}
+	tests := []struct {
+		name   string
+		params map[string]any
+		check  func(t *testing.T, merged map[string]any)
+	}{
+		{
+			"keeps unmanaged fields",
+			map[string]any{"cert": "new-cert", "snis": []string{"a.test"}},
+			func(t *testing.T, merged map[string]any) {
+				if merged["cert"] != "new-cert" || merged["client"] == nil || merged["ssl_protocols"] == nil || merged["create_time"] == nil {
+					t.Errorf("merged = %v", merged)
+				}
+				if _, ok := merged["update_time"]; ok {
+					t.Error("update_time kept")
+				}
+				if _, ok := merged["sni"]; ok {
+					t.Error("old scalar sni kept alongside snis")
+				}
+			},
+		},
+		{
+			"merges labels",
+			map[string]any{"labels": map[string]string{"not-after": "2030-01-01"}},
+			func(t *testing.T, merged map[string]any) {
+				labels, _ := merged["labels"].(map[string]any)
+				if labels["team"] != "edge" || labels["not-after"] != "2030-01-01" {
+					t.Errorf("labels = %v", labels)
+				}
+			},
+		},
+		{
+			"keeps labels when none written",
+			map[string]any{"cert": "new-cert"},
+			func(t *testing.T, merged map[string]any) {
+				labels, _ := merged["labels"].(map[string]any)
+				if labels["team"] != "edge" {
+					t.Errorf("labels = %v", labels)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.check(t, mergeSSL(existing, tt.params))
+			if labels, _ := existing["labels"].(map[string]any); labels["not-after"] != "2020-01-01" {
+				t.Fatal("mergeSSL modified the existing object")
+			}
+		})
+	}
+}
+
+// TestUploadBindMergeKeepsUnmanagedFields 合并 snis 时，其他工具设置的 label、client 与 ssl_protocols 保持不变
+func TestUploadBindMergeKeepsUnmanagedFields(t *testing.T) {
+	certPEM, keyPEM := testCert(t, "a.test", "b.test")
+	sha, err := GetSHA256(certPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stub := newAdminStub(t)
+	stub.putSSL("1", map[string]any{
+		"cert":          certPEM,
+		"key":           keyPEM,
+		"snis":          []any{"a.test"},
+		"desc":          managedPrefix + sha,
+		"labels":        map[string]any{"team": "edge"},
+		"client":        map[string]any{"ca": "CA", "depth": float64(2)},
+		"ssl_protocols": []any{"TLSv1.3"},
+	})
+	resp := runAction(t, "upload_bind", stub.params(map[string]any{
+		"cert": certPEM, "key": keyPEM, "domain": []any{"a.test", "b.test"}, "merge_snis": true,
+	}))
+	if resp.Status != "success" || resp.Result["action"] != "merged" {
+		t.Fatalf("upload_bind = %s %v", resp.Message, resp.Result)
+	}
+	if !stub.requested("GET /ssls/1") || !stub.requested("PUT /ssls/1") {
+		t.Errorf("requests = %v, want GET then PUT on /ssls/1", stub.requests)
+	}
+	stub.mu.Lock()
+	stored := stub.ssls["1"]
+	stub.mu.Unlock()
+	labels, _ := stored["labels"].(map[string]any)
+	client, _ := stored["client"].(map[string]any)
+	if labels["team"] != "edge" || labels["not-after"] == nil {
+		t.Errorf("labels = %v, want team kept and validity added", labels)
+	}
+	if client["ca"] != "CA" || stored["ssl_protocols"] == nil {
+		t.Errorf("stored = %v, want client and ssl_protocols kept", stored)
+	}
+	if snis, _ := snisFromValue(stored); strings.Join(snis, ",") != "a.test,b.test" {
+		t.Errorf("snis = %v", snis)
+	}
+}
+
+func TestUpdateCertReadFailure(t *testing.T) {
+	stub := newAdminStub(t)
+	a := NewAuth("test-key", stub.URL)
+	if _, err := a.updateCertToApisix("missing", "cert", "key", "", []string{"a.test"}, nil); err == nil {
+		t.Fatal("updateCertToApisix() succeeded without an existing object")
+	}
+	if stub.requested("PUT /ssls/missing") {
+		t.Error("PUT sent although the read failed")
+	}
+}
+
+func TestNumericValue(t *testing.T) {
+	tests := []struct {
+		name   string
+		in     any
+		want   int
+		wantOK bool
+	}{
+		{"float", float64(200), 200, true},
+		{"float with fraction", 200.5, 0, false},
+		{"int", 200, 200, true},
+		{"int64", int64(200), 200, true},
+		{"json number", json.Number("200"), 200, true},
+		{"json number float", json.Number("200.0"), 200, true},
+		{"json number fraction", json.Number("1.5"), 0, false},
+		{"string", "200", 200, true},
+		{"string float", " 200.0 ", 200, true},
+		{"string fraction", "1.5", 0, false},
+		{"string not a number", "ok", 0, false},
+		{"bool", true, 0, false},
+		{"nil", nil, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := numericValue(tt.in)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("numericValue(%#v) = %d, %v, want %d, %v", tt.in, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestAPIErrorCode(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{`{"code": 200, "error_msg": "x"}`, "200"},
+		{`{"code": "200", "error_msg": "x"}`, "200"},
+		{`{"code": 200.0, "error_msg": "x"}`, "200"},
+		{`{"code": 1.5, "error_msg": "x"}`, ""},
+		{`{"error_msg": "x"}`, ""},
+	}
+	for _, tt := range tests {
+		var result map[string]any
+		if err := json.Unmarshal([]byte(tt.body), &result); err != nil {
+			t.Fatal(err)
+		}
+		e := newAPIError(400, tt.body, result)
+		if e.APISIXCode != tt.want || e.APISIXMessage != "x" {
+			t.Errorf("newAPIError(%s) code = %q message = %q, want %q", tt.body, e.APISIXCode, e.APISIXMessage, tt.want)
+		}
+		// 使用 UseNumber 解码时数字为 json.Number，结果应一致
+		dec := json.NewDecoder(strings.NewReader(tt.body))
+		dec.UseNumber()
+		result = nil
+		if err := dec.Decode(&result); err != nil {
+			t.Fatal(err)
+		}
+		if e := newAPIError(400, tt.body, result); e.APISIXCode != tt.want {
+			t.Errorf("newAPIError(%s) with json.Number code = %q, want %q", tt.body, e.APISIXCode, tt.want)
+		}
+	}
+}
+
+func TestCreatedID(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		idPaths []string
+		body    string
+		want    string
+		wantOK  bool
+	}{
+		{"v3 key", "v3", nil, `{"key": "/apisix/ssls/42", "value": {"id": "42"}}`, "42", true},
+		{"v3 value id only", "v3", nil, `{"value": {"id": "42"}}`, "42", true},
+		{"numeric id", "v3", nil, `{"value": {"id": 42}}`, "42", true},
+		{"v2 node", "v2", nil, `{"action": "create", "node": {"key": "/apisix/ssl/42", "value": {}}}`, "42", true},
+		{"data key", "v3", nil, `{"data": {"key": "/apisix/ssls/42"}}`, "42", true},
+		{"data value id", "v3", nil, `{"data": {"value": {"id": "42"}}}`, "42", true},
+		{"data id", "v3", nil, `{"data": {"id": "42"}}`, "42", true},
+		{"data array", "v3", nil, `{"data": [{"key": "/apisix/ssls/42", "value": {}}]}`, "42", true},
+		{"nested data array", "v3", nil, `{"data": {"data": [{"value": {"id": "42"}}]}}`, "42", true},
+		{"id path", "v3", []string{"result.0.ssl_id"}, `{"result": [{"ssl_id": "42"}], "key": "/apisix/ssls/1"}`, "42", true},
+		{"id path miss falls back", "v3", []string{"result.id"}, `{"key": "/apisix/ssls/42"}`, "42", true},
+		{"no id", "v3", nil, `{"data": {"message": "ok"}}`, "", false},
+		{"empty data array", "v3", nil, `{"data": []}`, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var res map[string]any
+			if err := json.Unmarshal([]byte(tt.body), &res); err != nil {
+				t.Fatal(err)
+			}
+			a := Auth{APIVersion: tt.version, IDPaths: tt.idPaths}
+			got, ok := a.createdID(res)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("createdID(%s) = %q, %v, want %q, %v", tt.body, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestLookupPath(t *testing.T) {
+	var v any
+	if err := json.Unmarshal([]byte(`{"data": {"list": [{"id": "a"}, {"id": "b"}]}}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path   string
+		want   any
+		wantOK bool
+	}{
+		{"data.list.1.id", "b", true},
+		{"data.list.2.id", nil, false},
+		{"data.list.x", nil, false},
+		{"data.missing", nil, false},
+		{"data.list.0.id.more", nil, false},
+	}
+	for _, tt := range tests {
+		got, ok := lookupPath(v, tt.path)
+		if ok != tt.wantOK || (ok && got != tt.want) {
+			t.Errorf("lookupPath(%q) = %v, %v, want %v, %v", tt.path, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+// TestUploadCertResponseShapes 创建请求的响应为各种包裹形式时都能取到 id
+func TestUploadCertResponseShapes(t *testing.T) {
+	bodies := []string{
+		`{"key": "/apisix/ssls/7", "value": {"id": "7"}}`,
+		`{"data": {"value": {"id": "7"}}}`,
+		`{"data": [{"key": "/apisix/ssls/7"}]}`,
+	}
+	for _, body := range bodies {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(body))
+		}))
+		a := NewAuth("test-key", srv.URL)
+		id, err := a.uploadCertToApisix("cert", "key", "", []string{"a.test"}, nil)
+		srv.Close()
+		if err != nil || id != "7" {
+			t.Errorf("uploadCertToApisix() with %s = %q, %v, want 7", body, id, err)
+		}
+	}
+}
+
+func TestDeleteCertDeletedField(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr bool
+	}{
+		{"string", `{"deleted": "1", "key": "/apisix/ssls/7"}`, false},
+		{"number", `{"deleted": 1, "key": "/apisix/ssls/7"}`, false},
+		{"float", `{"deleted": 1.0, "key": "/apisix/ssls/7"}`, false},
+		{"more than one", `{"deleted": 2, "key": "/apisix/ssls/7"}`, false},
+		{"zero", `{"deleted": 0, "key": "/apisix/ssls/7"}`, true},
+		{"string zero", `{"deleted": "0", "key": "/apisix/ssls/7"}`, true},
+		{"missing", `{"key": "/apisix/ssls/7"}`, true},
+		{"other key", `{"deleted": "1", "key": "/apisix/ssls/8"}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+			a := NewAuth("test-key", srv.URL)
+			ok, err := a.DeleteCertFromApisix("7")
+			if tt.wantErr {
+				if err == nil || ok {
+					t.Fatalf("DeleteCertFromApisix() = %v, %v, want an error", ok, err)
+				}
+				return
+			}
+			if err != nil || !ok {
+				t.Fatalf("DeleteCertFromApisix() = %v, %v", ok, err)
+			}
+		})
+	}
+}
+
+func TestDeleteCertDeletedFieldV2(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"action": "delete", "deleted": "1", "node": {"key": "/apisix/ssl/7"}}`))
+	}))
+	defer srv.Close()
+	a := NewAuth("test-key", srv.URL, func(a *Auth) { a.APIVersion = "v2" })
+	if ok, err := a.DeleteCertFromApisix("7"); err != nil || !ok {
+		t.Fatalf("DeleteCertFromApisix() = %v, %v", ok, err)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// domainAllowlist 限定插件可以操作的域名，为空表示不限制。
+// 条目可以是后缀（example.com 匹配自身及所有子域名）或通配模式（如 *.example.com、api-*.example.com）
+type domainAllowlist []string
+
+// allowlistParam 读取 domain_allowlist 参数
+func allowlistParam(cfg map[string]any) (domainAllowlist, error) {
+	v, ok := cfg["domain_allowlist"]
+	if !ok || v == nil {
+		return nil, nil
+	}
+	list, ok := v.([]any)
+	if !ok {
+		return nil, fmt.Errorf("domain_allowlist must be an array of strings")
+	}
+	allow := make(domainAllowlist, 0, len(list))
+	for i, item := range list {
+		s, ok := item.(string)
+		if !ok || strings.TrimSpace(s) == "" {
+			return nil, fmt.Errorf("domain_allowlist element at index %d is not a string", i)
+		}
+		s = strings.ToLower(strings.TrimSpace(s))
+		if _, err := path.Match(s, ""); err != nil {
+			return nil, fmt.Errorf("invalid domain_allowlist pattern %q: %w", s, err)
+		}
+		allow = append(allow, s)
+	}
+	return allow, nil
+}
+
+// allows 判断单个域名是否在允许列表内
+func (l domainAllowlist) allows(domain string) bool {
+	if len(l) == 0 {
+		return true
+	}
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	for _, p := range l {
+		if strings.Contains(p, "*") {
+			if ok, _ := path.Match(p, domain); ok {
+				return true
+			}
+			continue
+		}
+		p = strings.TrimPrefix(p, ".")
+		if domain == p || strings.HasSuffix(domain, "."+p) {
+			return true
+		}
+	}
+	return false
+}
+
+// check 要求 domains 全部在允许列表内，否则返回列出越界域名的错误
+func (l domainAllowlist) check(domains []string) error {
+	if len(l) == 0 {
+		return nil
+	}
+	outside := make([]string, 0)
+	for _, d := range domains {
+		if !l.allows(d) {
+			outside = append(outside, d)
+		}
+	}
+	if len(outside) > 0 {
+		return fmt.Errorf("domains %v are outside domain_allowlist %v", outside, []string(l))
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+// CertBackend 抽象对网关 SSL 对象的增删查操作，
+// Upload_bind 等动作只依赖该接口，不关心具体后端。
+// uploadCertToApisix 的 extra 为附加写入 SSL 对象的可选字段（如 labels）
+type CertBackend interface {
+	listCertFromApisix() ([]map[string]any, error)
+	uploadCertToApisix(cert, key, note string, domain []string, extra map[string]any) (string, error)
+	updateCertToApisix(certKey, cert, key, note string, domain []string, extra map[string]any) (string, error)
+	DeleteCertFromApisix(certKey string) (bool, error)
+}
+
+// sniBinder 由支持单独更新 snis（不改动证书与私钥）的后端实现
+type sniBinder interface {
+	bindSNIs(certKey string, domain []string) error
+}
+
+// sslRestorer 由支持按原 id 原样写回 SSL 对象的后端实现，用于回滚已删除的证书
+type sslRestorer interface {
+	restoreSSL(id string, value map[string]any) error
+}
+
+// routeLister 由支持读取路由的后端实现，用于检查证书是否仍被路由引用
+type routeLister interface {
+	listRoutes() ([]map[string]any, error)
+}
+
+// timingRecorder 由支持 verbose 耗时统计的后端实现
+type timingRecorder interface {
+	Timings() map[string]callTiming
+}
+
+// callTiming 为同一 "METHOD path" 的调用汇总：次数、总耗时与最长耗时（毫秒）
+type callTiming struct {
+	Count   int     `json:"count"`
+	TotalMs float64 `json:"total_ms"`
+	MaxMs   float64 `json:"max_ms"`
+}
+
+// timingLog 累计各次 API 调用耗时；同一路径的多次调用（如分页、逐个删除）累加而不是互相覆盖
+type timingLog struct {
+	mu    sync.Mutex
+	calls map[string]*callTiming
+}
+
+func (t *timingLog) add(key string, ms float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	c, ok := t.calls[key]
+	if !ok {
+		c = &callTiming{}
+		t.calls[key] = c
+	}
+	c.Count++
+	c.TotalMs += ms
+	if ms > c.MaxMs {
+		c.MaxMs = ms
+	}
+}
+
+func (t *timingLog) snapshot() map[string]callTiming {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make(map[string]callTiming, len(t.calls))
+	for k, c := range t.calls {
+		out[k] = *c
+	}
+	return out
+}
+
+// rawRecorder 由支持 debug 原始响应记录的后端实现
+type rawRecorder interface {
+	RawResponses() []map[string]any
+}
+
+// attachDiagnostics 将诊断信息写入返回结果：verbose 时附带各次 API 调用耗时，
+// debug 时附带脱敏后的原始响应，发生过 429 重试时附带 retry_info
+func attachDiagnostics(b CertBackend, result map[string]any) {
+	if r, ok := b.(timingRecorder); ok && r.Timings() != nil {
+		result["timings"] = r.Timings()
+	}
+	if r, ok := b.(rawRecorder); ok && r.RawResponses() != nil {
+		result["raw"] = r.RawResponses()
+	}
+	if r, ok := b.(retryRecorder); ok && r.RetryInfo() != nil {
+		result["retry_info"] = r.RetryInfo()
+	}
+}
+
+// backendFromParams 根据 backend 参数选择后端：admin_api（默认）或 dashboard
+func backendFromParams(cfg map[string]any) (CertBackend, error) {
+	backend, _ := cfg["backend"].(string)
+	var b CertBackend
+	var err error
+	switch backend {
+	case "", "admin_api":
+		b, err = authFromParams(cfg)
+	case "dashboard":
+		b, err = dashboardFromParams(cfg)
+	default:
+		return nil, fmt.Errorf("unsupported backend: %s", backend)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
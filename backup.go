@@ -0,0 +1,263 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// 备份文件格式版本
+const backupVersion = 1
+
+// Backup 导出所有由插件托管的 SSL 对象（cert/key 按服务器存储原样导出）
+func Backup(cfg map[string]any) (*Response, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config cannot be nil")
+	}
+	a, err := backendFromParams(cfg)
+	if err != nil {
+		return nil, err
+	}
+	certServer, err := a.listCertFromApisix()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list certs from Apisix: %w", err)
+	}
+	ssls := make([]map[string]any, 0, len(certServer))
+	for _, cert := range certServer {
+		value, ok := cert["value"].(map[string]any)
+		if !ok || !isManagedCert(value) {
+			continue
+		}
+		ssls = append(ssls, value)
+	}
+	backup := backupDocument(ssls)
+
+	result := map[string]any{
+		"count":  len(ssls),
+		"backup": backup,
+	}
+	if backupFile, ok := cfg["backup_file"].(string); ok && backupFile != "" {
+		if err := writeBackupFile(backupFile, backup); err != nil {
+			return nil, err
+		}
+		result["backup_file"] = backupFile
+	}
+	attachDiagnostics(a, result)
+	return &Response{
+		Status:  "success",
+		Message: "Certificates backed up successfully",
+		Result:  result,
+	}, nil
+}
+
+// backupDocument 按备份文件格式组装 SSL 对象列表
+func backupDocument(ssls []map[string]any) map[string]any {
+	return map[string]any{
+		"version":    backupVersion,
+		"created_at": time.Now().UTC().Format(time.RFC3339),
+		"ssls":       ssls,
+	}
+}
+
+// writeBackupFile 将备份写入文件；备份中可能包含私钥，仅允许当前用户读写
+func writeBackupFile(path string, backup map[string]any) error {
+	data, err := json.MarshalIndent(backup, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode backup: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write backup file: %w", err)
+	}
+	return nil
+}
+
+// backupKey 返回备份条目的私钥：优先使用 keys 中按证书 id 或指纹提供的私钥，其次使用条目自带的 key
+func backupKey(value map[string]any, keys map[string]string, sha256 string) string {
+	id, _ := value["id"].(string)
+	if k := keys[id]; k != "" {
+		return k
+	}
+	if k := keys[sha256]; k != "" {
+		return k
+	}
+	keyStr, _ := value["key"].(string)
+	return keyStr
+}
+
+// loadBackup 从 backup 参数（对象或 JSON 字符串）或 backup_file 读取备份内容
+func loadBackup(cfg map[string]any) (map[string]any, error) {
+	switch v := cfg["backup"].(type) {
+	case map[string]any:
+		return v, nil
+	case string:
+		if v != "" {
+			var backup map[string]any
+			if err := json.Unmarshal([]byte(v), &backup); err != nil {
+				return nil, fmt.Errorf("backup is not valid JSON: %w", err)
+			}
+			return backup, nil
+		}
+	}
+	backupFile, ok := cfg["backup_file"].(string)
+	if !ok || backupFile == "" {
+		return nil, fmt.Errorf("backup or backup_file is required")
+	}
+	data, err := os.ReadFile(backupFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup file: %w", err)
+	}
+	var backup map[string]any
+	if err := json.Unmarshal(data, &backup); err != nil {
+		return nil, fmt.Errorf("backup file is not valid JSON: %w", err)
+	}
+	return backup, nil
+}
+
+// Restore 根据备份重新创建 SSL 对象，已存在（指纹相同）的证书会被跳过
+func Restore(cfg map[string]any) (*Response, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config cannot be nil")
+	}
+	stop := installSignalHandler()
+	defer stop()
+	a, err := backendFromParams(cfg)
+	if err != nil {
+		return nil, err
+	}
+	backup, err := loadBackup(cfg)
+	if err != nil {
+		return nil, err
+	}
+	ssls, ok := backup["ssls"].([]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid backup format: ssls not found")
+	}
+	// 可选：按证书 id 或指纹补充私钥（APISIX 通常不会返回私钥）
+	keys, err := stringMapParam(cfg, "keys")
+	if err != nil {
+		return nil, err
+	}
+
+	certServer, err := a.listCertFromApisix()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list certs from Apisix: %w", err)
+	}
+	existing := make(map[string]bool)
+	for _, cert := range certServer {
+		value, ok := cert["value"].(map[string]any)
+		if !ok {
+			continue
+		}
+		if certStr, ok := value["cert"].(string); ok {
+			if sha256, err := GetSHA256(certStr); err == nil {
+				existing[sha256] = true
+			}
+		}
+		if isManagedCert(value) {
+			existing[managedMark(value)[len(managedPrefix):]] = true
+		}
+	}
+
+	results := make([]map[string]any, 0, len(ssls))
+	restored, skipped, failed := 0, 0, 0
+	aborted := 0
+	for i, item := range ssls {
+		entry := map[string]any{"index": i}
+		results = append(results, entry)
+		// 收到中断信号后不再发起新的恢复，剩余条目标记为 aborted
+		if interrupted() {
+			entry["status"] = "aborted"
+			aborted++
+			continue
+		}
+		value, ok := item.(map[string]any)
+		if !ok {
+			entry["status"] = "failed"
+			entry["error"] = "ssl item is not a map"
+			failed++
+			continue
+		}
+		id, _ := value["id"].(string)
+		entry["id"] = id
+		certStr, _ := value["cert"].(string)
+		sha256, err := GetSHA256(certStr)
+		if err != nil {
+			entry["status"] = "failed"
+			entry["error"] = fmt.Sprintf("failed to get SHA256 of cert: %v", err)
+			failed++
+			continue
+		}
+		entry["fingerprint"] = sha256
+		if existing[sha256] {
+			entry["status"] = "skipped"
+			skipped++
+			continue
+		}
+		keyStr := backupKey(value, keys, sha256)
+		if keyStr == "" {
+			entry["status"] = "failed"
+			entry["error"] = "private key is missing from backup (APISIX does not return keys); supply it via the keys param keyed by cert id or fingerprint"
+			failed++
+			continue
+		}
+		snis, ok := snisFromValue(value)
+		if !ok || len(snis) == 0 {
+			entry["status"] = "failed"
+			entry["error"] = "snis is missing or invalid in backup"
+			failed++
+			continue
+		}
+		desc, _ := value["desc"].(string)
+		if desc == "" && managedByField == "desc" {
+			desc = managedPrefix + sha256
+		}
+		extra := map[string]any{}
+		if labels, ok := value["labels"].(map[string]any); ok {
+			extra["labels"] = labels
+		}
+		if managedByField == "labels" && !isManagedCert(value) {
+			labels, _ := extra["labels"].(map[string]any)
+			merged := map[string]any{managedByLabel: managedPrefix + sha256}
+			for k, v := range labels {
+				merged[k] = v
+			}
+			extra["labels"] = merged
+		}
+		certKey, err := a.uploadCertToApisix(certStr, keyStr, desc, snis, extra)
+		if err != nil {
+			entry["status"] = "failed"
+			entry["error"] = err.Error()
+			failed++
+			continue
+		}
+		entry["status"] = "restored"
+		entry["new_id"] = certKey
+		existing[sha256] = true
+		restored++
+	}
+
+	status := "success"
+	message := "Certificates restored successfully"
+	if failed > 0 {
+		status = "error"
+		message = fmt.Sprintf("%d certificate(s) failed to restore", failed)
+	}
+	if aborted > 0 {
+		status = "partial"
+		message = fmt.Sprintf("interrupted: %d certificate(s) were not attempted", aborted)
+	}
+	result := map[string]any{
+		"restored": restored,
+		"skipped":  skipped,
+		"failed":   failed,
+		"aborted":  aborted,
+		"results":  results,
+	}
+	attachDiagnostics(a, result)
+	return &Response{
+		Status:  status,
+		Message: message,
+		Result:  result,
+	}, nil
+}
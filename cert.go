@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// validateKeyPair 校验证书与私钥是否匹配，并返回证书公钥算法（RSA/ECDSA/Ed25519）
+// tls.X509KeyPair 支持 PKCS#1、PKCS#8 与 SEC1（EC PRIVATE KEY）格式的私钥
+func validateKeyPair(certStr, keyStr string) (string, error) {
+	pair, err := tls.X509KeyPair([]byte(certStr), []byte(keyStr))
+	if err != nil {
+		return "", fmt.Errorf("cert and key do not match: %w", err)
+	}
+	leaf := pair.Leaf
+	if leaf == nil {
+		leaf, err = x509.ParseCertificate(pair.Certificate[0])
+		if err != nil {
+			return "", fmt.Errorf("failed to parse cert: %w", err)
+		}
+	}
+	return keyAlgorithm(leaf)
+}
+
+// keyAlgorithm 返回证书公钥算法名称，不支持的算法返回错误
+func keyAlgorithm(cert *x509.Certificate) (string, error) {
+	switch cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return "RSA", nil
+	case *ecdsa.PublicKey:
+		return "ECDSA", nil
+	case ed25519.PublicKey:
+		return "Ed25519", nil
+	default:
+		return "", fmt.Errorf("unsupported public key algorithm: %s", cert.PublicKeyAlgorithm)
+	}
+}
+
+// parseLeaf 解析 PEM 中的第一张证书：跳过开头的无关内容以及私钥等非 CERTIFICATE 块，
+// 证书与私钥合并在同一个 PEM 中（且私钥在前）时也能取到正确的证书
+func parseLeaf(certStr string) (*x509.Certificate, error) {
+	rest := []byte(certStr)
+	var block *pem.Block
+	found := false
+	for {
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type == "CERTIFICATE" {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("无法解析证书 PEM: 未找到 CERTIFICATE 类型的 PEM 块")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("解析证书失败: %v", err)
+	}
+	return cert, nil
+}
+
+// 默认允许的最短 RSA 密钥长度
+const defaultMinRSABits = 2048
+
+// checkCertPolicy 检查弱证书：RSA 密钥过短或使用 SHA-1/MD5 签名，返回告警列表
+func checkCertPolicy(cert *x509.Certificate, minRSABits int, allowSHA1 bool) []string {
+	warnings := make([]string, 0)
+	if pub, ok := cert.PublicKey.(*rsa.PublicKey); ok && pub.N.BitLen() < minRSABits {
+		warnings = append(warnings, fmt.Sprintf("RSA key is %d bits, shorter than %d", pub.N.BitLen(), minRSABits))
+	}
+	switch cert.SignatureAlgorithm {
+	case x509.MD5WithRSA:
+		warnings = append(warnings, fmt.Sprintf("certificate is signed with %s", cert.SignatureAlgorithm))
+	case x509.SHA1WithRSA, x509.ECDSAWithSHA1, x509.DSAWithSHA1:
+		if !allowSHA1 {
+			warnings = append(warnings, fmt.Sprintf("certificate is signed with %s", cert.SignatureAlgorithm))
+		}
+	}
+	return warnings
+}
+
+// validityLabels 根据证书有效期生成 APISIX labels（UTC 日期）
+func validityLabels(cert *x509.Certificate) map[string]string {
+	return map[string]string{
+		"not-before": cert.NotBefore.UTC().Format("2006-01-02"),
+		"not-after":  cert.NotAfter.UTC().Format("2006-01-02"),
+	}
+}
+
+// snisFromCert 从证书中提取可用作 SNI 的主机名：优先使用 DNS SAN，
+// 没有 SAN 时退回 CN；IP SAN 不能作为 SNI，直接忽略
+func snisFromCert(cert *x509.Certificate) []string {
+	if len(cert.DNSNames) > 0 {
+		return normalizeDomains(cert.DNSNames)
+	}
+	cn := cert.Subject.CommonName
+	if cn == "" || net.ParseIP(cn) != nil {
+		return []string{}
+	}
+	return normalizeDomains([]string{cn})
+}
+
+// certCoversDomain 判断证书的 DNS SAN（没有 SAN 时为 CN）是否覆盖 domain，
+// 支持单级通配符：*.example.com 覆盖 www.example.com，不覆盖 example.com
+func certCoversDomain(cert *x509.Certificate, domain string) bool {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	names := cert.DNSNames
+	if len(names) == 0 && cert.Subject.CommonName != "" {
+		names = []string{cert.Subject.CommonName}
+	}
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSuffix(name, "."))
+		if name == domain {
+			return true
+		}
+		if strings.HasPrefix(name, "*.") {
+			if i := strings.Index(domain, "."); i > 0 && domain[i:] == name[1:] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseChain 解析 PEM 中的全部证书，保持原有顺序
+func parseChain(certStr string) ([]*x509.Certificate, error) {
+	rest := []byte(certStr)
+	chain := make([]*x509.Certificate, 0)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("解析证书失败: %v", err)
+		}
+		chain = append(chain, cert)
+	}
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("无法解析证书 PEM")
+	}
+	return chain, nil
+}
+
+// encodeChain 将证书链编码为 PEM
+func encodeChain(chain []*x509.Certificate) string {
+	var b strings.Builder
+	for _, cert := range chain {
+		_ = pem.Encode(&b, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	}
+	return b.String()
+}
+
+// issuedBy 判断 child 是否由 parent 签发
+func issuedBy(child, parent *x509.Certificate) bool {
+	return bytes.Equal(child.RawIssuer, parent.RawSubject) && child.CheckSignatureFrom(parent) == nil
+}
+
+// reorderChain 将证书链整理为叶子证书在前、逐级向上的顺序；
+// 叶子证书为未签发链中其他证书的那一张，无法唯一确定时返回错误
+func reorderChain(chain []*x509.Certificate) ([]*x509.Certificate, error) {
+	if len(chain) < 2 {
+		return chain, nil
+	}
+	var leaves []*x509.Certificate
+	for _, c := range chain {
+		isIssuer := false
+		for _, other := range chain {
+			if other != c && issuedBy(other, c) {
+				isIssuer = true
+				break
+			}
+		}
+		if !isIssuer {
+			leaves = append(leaves, c)
+		}
+	}
+	if len(leaves) != 1 {
+		return nil, fmt.Errorf("cannot determine leaf certificate: found %d candidates", len(leaves))
+	}
+	ordered := []*x509.Certificate{leaves[0]}
+	used := map[*x509.Certificate]bool{leaves[0]: true}
+	for len(ordered) < len(chain) {
+		cur := ordered[len(ordered)-1]
+		var next *x509.Certificate
+		for _, c := range chain {
+			if !used[c] && issuedBy(cur, c) {
+				next = c
+				break
+			}
+		}
+		if next == nil {
+			// 剩余证书与链无关，保持原顺序附在末尾
+			for _, c := range chain {
+				if !used[c] {
+					ordered = append(ordered, c)
+					used[c] = true
+				}
+			}
+			break
+		}
+		ordered = append(ordered, next)
+		used[next] = true
+	}
+	return ordered, nil
+}
+
+// verifyChain 校验证书链顺序并确认能构建到受信任的根证书；roots 为 nil 时使用系统根证书
+func verifyChain(chain []*x509.Certificate, roots *x509.CertPool) error {
+	for i := 0; i+1 < len(chain); i++ {
+		if !issuedBy(chain[i], chain[i+1]) {
+			return fmt.Errorf("certificate chain is out of order: %q is not issued by %q", chain[i].Subject.CommonName, chain[i+1].Subject.CommonName)
+		}
+	}
+	intermediates := x509.NewCertPool()
+	for _, c := range chain[1:] {
+		intermediates.AddCert(c)
+	}
+	_, err := chain[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+	})
+	if err != nil {
+		return fmt.Errorf("certificate chain does not verify: %w", err)
+	}
+	return nil
+}
+
+// checkChain 根据 ca_bundle 参数（PEM，缺省使用系统根证书）校验证书链
+func checkChain(cfg map[string]any, certStr string) error {
+	chain, err := parseChain(certStr)
+	if err != nil {
+		return err
+	}
+	var roots *x509.CertPool
+	if bundle, ok := cfg["ca_bundle"].(string); ok && bundle != "" {
+		roots = x509.NewCertPool()
+		if !roots.AppendCertsFromPEM([]byte(bundle)) {
+			return fmt.Errorf("ca_bundle contains no valid certificates")
+		}
+	}
+	return verifyChain(chain, roots)
+}
@@ -0,0 +1,427 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testKeyCert 使用给定私钥生成自签证书，keyPEM 按 keyType 编码（PKCS1、PKCS8 或 SEC1）
+func testKeyCert(t *testing.T, signer crypto.Signer, keyType string, names ...string) (string, string) {
+	t.Helper()
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: names[0]},
+		DNSNames:     names,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(90 * 24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, signer.Public(), signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var block *pem.Block
+	switch keyType {
+	case "RSA PRIVATE KEY":
+		block = &pem.Block{Type: keyType, Bytes: x509.MarshalPKCS1PrivateKey(signer.(*rsa.PrivateKey))}
+	case "EC PRIVATE KEY":
+		b, err := x509.MarshalECPrivateKey(signer.(*ecdsa.PrivateKey))
+		if err != nil {
+			t.Fatal(err)
+		}
+		block = &pem.Block{Type: keyType, Bytes: b}
+	default:
+		b, err := x509.MarshalPKCS8PrivateKey(signer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: b}
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	return string(certPEM), string(pem.EncodeToMemory(block))
+}
+
+// testSigners 返回各算法的私钥，RSA 只生成一次以节省时间
+func testSigners(t *testing.T) map[string]crypto.Signer {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]crypto.Signer{"RSA": rsaKey, "ECDSA": ecKey, "Ed25519": edKey}
+}
+
+func TestValidateKeyPair(t *testing.T) {
+	signers := testSigners(t)
+	tests := []struct {
+		name    string
+		alg     string
+		keyType string
+		want    string
+	}{
+		{"rsa pkcs1", "RSA", "RSA PRIVATE KEY", "RSA"},
+		{"rsa pkcs8", "RSA", "PRIVATE KEY", "RSA"},
+		{"ecdsa sec1", "ECDSA", "EC PRIVATE KEY", "ECDSA"},
+		{"ecdsa pkcs8", "ECDSA", "PRIVATE KEY", "ECDSA"},
+		{"ed25519 pkcs8", "Ed25519", "PRIVATE KEY", "Ed25519"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			certPEM, keyPEM := testKeyCert(t, signers[tt.alg], tt.keyType, "a.test")
+			got, err := validateKeyPair(certPEM, keyPEM)
+			if err != nil {
+				t.Fatalf("validateKeyPair() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("validateKeyPair() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	t.Run("mismatch", func(t *testing.T) {
+		certPEM, _ := testKeyCert(t, signers["ECDSA"], "EC PRIVATE KEY", "a.test")
+		_, otherKey := testKeyCert(t, signers["Ed25519"], "PRIVATE KEY", "a.test")
+		if _, err := validateKeyPair(certPEM, otherKey); err == nil {
+			t.Fatal("validateKeyPair() accepted a key that does not match the cert")
+		}
+	})
+}
+
+// TestUploadBindKeyTypes 以完整的 upload_bind 流程上传各算法的证书
+func TestUploadBindKeyTypes(t *testing.T) {
+	signers := testSigners(t)
+	for _, alg := range []string{"RSA", "ECDSA", "Ed25519"} {
+		t.Run(alg, func(t *testing.T) {
+			stub := newAdminStub(t)
+			certPEM, keyPEM := testKeyCert(t, signers[alg], "PRIVATE KEY", strings.ToLower(alg)+".test")
+			resp := runAction(t, "upload_bind", stub.params(map[string]any{"cert": certPEM, "key": keyPEM}))
+			if resp.Status != "success" {
+				t.Fatalf("upload_bind: %s", resp.Message)
+			}
+			if resp.Result["key_type"] != alg {
+				t.Errorf("key_type = %v, want %s", resp.Result["key_type"], alg)
+			}
+			id, _ := resp.Result["cert_id"].(string)
+			stub.mu.Lock()
+			stored := stub.ssls[id]
+			stub.mu.Unlock()
+			if stored == nil || stored["key"] != keyPEM {
+				t.Errorf("stored ssl %s = %v, want the uploaded key", id, stored)
+			}
+		})
+	}
+}
+
+func TestCheckCertPolicy(t *testing.T) {
+	signers := testSigners(t)
+	smallRSA, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		pub       any
+		sig       x509.SignatureAlgorithm
+		allowSHA1 bool
+		want      []string
+	}{
+		{"rsa 2048", signers["RSA"].Public(), x509.SHA256WithRSA, false, nil},
+		{"rsa 1024", &smallRSA.PublicKey, x509.SHA256WithRSA, false, []string{"RSA key is 1024 bits"}},
+		{"ecdsa", signers["ECDSA"].Public(), x509.ECDSAWithSHA256, false, nil},
+		{"ed25519", signers["Ed25519"].Public(), x509.PureEd25519, false, nil},
+		{"sha1", signers["RSA"].Public(), x509.SHA1WithRSA, false, []string{"SHA1-RSA"}},
+		{"sha1 allowed", signers["RSA"].Public(), x509.SHA1WithRSA, true, nil},
+		{"ecdsa sha1", signers["ECDSA"].Public(), x509.ECDSAWithSHA1, false, []string{"ECDSA-SHA1"}},
+		{"md5 always", signers["RSA"].Public(), x509.MD5WithRSA, true, []string{"MD5-RSA"}},
+		{"short and sha1", &smallRSA.PublicKey, x509.SHA1WithRSA, false, []string{"1024 bits", "SHA1-RSA"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert := &x509.Certificate{PublicKey: tt.pub, SignatureAlgorithm: tt.sig}
+			got := checkCertPolicy(cert, defaultMinRSABits, tt.allowSHA1)
+			if len(got) != len(tt.want) {
+				t.Fatalf("checkCertPolicy() = %v, want %d warnings", got, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(got[i], want) {
+					t.Errorf("warning %d = %q, want it to contain %q", i, got[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestSnisFromCert(t *testing.T) {
+	tests := []struct {
+		name string
+		cert *x509.Certificate
+		want []string
+	}{
+		{
+			"dns and ip sans",
+			&x509.Certificate{DNSNames: []string{"A.test", "b.test", "a.test"}, IPAddresses: []net.IP{net.ParseIP("10.0.0.1")}},
+			[]string{"a.test", "b.test"},
+		},
+		{"cn fallback", &x509.Certificate{Subject: pkix.Name{CommonName: "C.test"}}, []string{"c.test"}},
+		{"sans win over cn", &x509.Certificate{Subject: pkix.Name{CommonName: "cn.test"}, DNSNames: []string{"san.test"}}, []string{"san.test"}},
+		{"ip cn", &x509.Certificate{Subject: pkix.Name{CommonName: "10.0.0.1"}}, []string{}},
+		{"ip sans only", &x509.Certificate{IPAddresses: []net.IP{net.ParseIP("10.0.0.1")}}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := snisFromCert(tt.cert)
+			if got == nil || strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("snisFromCert() = %#v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCertCoversDomain(t *testing.T) {
+	sans := &x509.Certificate{DNSNames: []string{"example.test", "*.Wild.test"}, IPAddresses: []net.IP{net.ParseIP("10.0.0.1")}}
+	cnOnly := &x509.Certificate{Subject: pkix.Name{CommonName: "cn.test"}}
+	tests := []struct {
+		name   string
+		cert   *x509.Certificate
+		domain string
+		want   bool
+	}{
+		{"exact", sans, "example.test", true},
+		{"case and trailing dot", sans, "EXAMPLE.test.", true},
+		{"wildcard one level", sans, "www.wild.test", true},
+		{"wildcard apex", sans, "wild.test", false},
+		{"wildcard two levels", sans, "a.b.wild.test", false},
+		{"unrelated", sans, "other.test", false},
+		{"ip san", sans, "10.0.0.1", false},
+		{"cn fallback", cnOnly, "cn.test", true},
+		{"cn ignored with sans", &x509.Certificate{Subject: pkix.Name{CommonName: "cn.test"}, DNSNames: []string{"san.test"}}, "cn.test", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := certCoversDomain(tt.cert, tt.domain); got != tt.want {
+				t.Errorf("certCoversDomain(%q) = %v, want %v", tt.domain, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestUploadBindIPSANs 证书同时包含 DNS 与 IP SAN：自动提取的 snis 只含主机名，显式传入 IP 时报错
+func TestUploadBindIPSANs(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "ip.test"},
+		DNSNames:     []string{"ip.test", "www.ip.test"},
+		IPAddresses:  []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("::1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(90 * 24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, _ := x509.MarshalPKCS8PrivateKey(key)
+	certPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	keyPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}))
+
+	tests := []struct {
+		name     string
+		domain   any
+		wantSNIs []any
+		wantErr  string
+	}{
+		{"derived", nil, []any{"ip.test", "www.ip.test"}, ""},
+		{"explicit ip", []any{"ip.test", "10.0.0.1"}, nil, "SNI requires hostnames"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newAdminStub(t)
+			params := map[string]any{"cert": certPEM, "key": keyPEM}
+			if tt.domain != nil {
+				params["domain"] = tt.domain
+			}
+			resp := runAction(t, "upload_bind", stub.params(params))
+			if tt.wantErr != "" {
+				if resp.Status != "error" || !strings.Contains(resp.Message, tt.wantErr) {
+					t.Fatalf("upload_bind = %s %q, want error containing %q", resp.Status, resp.Message, tt.wantErr)
+				}
+				if ids := stub.sslIDs(); len(ids) != 0 {
+					t.Errorf("ssl objects created despite the error: %v", ids)
+				}
+				return
+			}
+			if resp.Status != "success" {
+				t.Fatalf("upload_bind: %s", resp.Message)
+			}
+			snis, _ := resp.Result["snis"].([]any)
+			if len(snis) != len(tt.wantSNIs) || snis[0] != tt.wantSNIs[0] || snis[1] != tt.wantSNIs[1] {
+				t.Errorf("snis = %v, want %v", snis, tt.wantSNIs)
+			}
+		})
+	}
+}
+
+func TestGetSHA256(t *testing.T) {
+	certPEM, keyPEM := testCert(t, "a.test")
+	block, _ := pem.Decode([]byte(certPEM))
+	sum := sha256.Sum256(block.Bytes)
+	want := hex.EncodeToString(sum[:])
+	other, _ := testCert(t, "b.test")
+	tests := []struct {
+		name    string
+		in      string
+		wantErr bool
+	}{
+		{"cert only", certPEM, false},
+		{"cert then key", certPEM + keyPEM, false},
+		{"key then cert", keyPEM + certPEM, false},
+		{"leading junk", "subject=CN=a.test\nissuer=CN=a.test\n" + certPEM, false},
+		{"crlf", strings.ReplaceAll(keyPEM+certPEM, "\n", "\r\n"), false},
+		{"first cert wins", certPEM + other, false},
+		{"key only", keyPEM, true},
+		{"empty", "", true},
+		{"garbage", "not a pem", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetSHA256(tt.in)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "CERTIFICATE") {
+					t.Fatalf("GetSHA256() error = %v, want an error naming the missing CERTIFICATE block", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetSHA256() error = %v", err)
+			}
+			if got != want {
+				t.Errorf("GetSHA256() = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+// testChain 生成 根 -> 中间 -> 叶子 三级证书链，返回顺序为 [leaf, intermediate, root]
+func testChain(t *testing.T) []*x509.Certificate {
+	t.Helper()
+	newKey := func() *ecdsa.PrivateKey {
+		k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+	issue := func(tmpl, parent *x509.Certificate, pub any, signer *ecdsa.PrivateKey) *x509.Certificate {
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, pub, signer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cert
+	}
+	ca := func(serial int64, cn string) *x509.Certificate {
+		return &x509.Certificate{
+			SerialNumber:          big.NewInt(serial),
+			Subject:               pkix.Name{CommonName: cn},
+			NotBefore:             time.Now().Add(-time.Hour),
+			NotAfter:              time.Now().Add(24 * time.Hour),
+			IsCA:                  true,
+			BasicConstraintsValid: true,
+			KeyUsage:              x509.KeyUsageCertSign,
+		}
+	}
+	rootKey, interKey, leafKey := newKey(), newKey(), newKey()
+	rootTmpl := ca(1, "root")
+	root := issue(rootTmpl, rootTmpl, &rootKey.PublicKey, rootKey)
+	inter := issue(ca(2, "intermediate"), root, &interKey.PublicKey, rootKey)
+	leaf := issue(&x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "a.test"},
+		DNSNames:     []string{"a.test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}, inter, &leafKey.PublicKey, interKey)
+	return []*x509.Certificate{leaf, inter, root}
+}
+
+func TestReorderChain(t *testing.T) {
+	chain := testChain(t)
+	leaf, inter, root := chain[0], chain[1], chain[2]
+	unrelated := testChain(t)[2]
+	tests := []struct {
+		name    string
+		in      []*x509.Certificate
+		want    []*x509.Certificate
+		wantErr bool
+	}{
+		{"single", []*x509.Certificate{leaf}, []*x509.Certificate{leaf}, false},
+		{"ordered", []*x509.Certificate{leaf, inter, root}, []*x509.Certificate{leaf, inter, root}, false},
+		{"reversed", []*x509.Certificate{root, inter, leaf}, []*x509.Certificate{leaf, inter, root}, false},
+		{"leaf in middle", []*x509.Certificate{inter, leaf, root}, []*x509.Certificate{leaf, inter, root}, false},
+		{"without root", []*x509.Certificate{inter, leaf}, []*x509.Certificate{leaf, inter}, false},
+		{"two leaves", []*x509.Certificate{leaf, unrelated}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := reorderChain(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("reorderChain() = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("reorderChain() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("reorderChain() returned %d certs, want %d", len(got), len(tt.want))
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("position %d = %s, want %s", i, got[i].Subject.CommonName, tt.want[i].Subject.CommonName)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyChain(t *testing.T) {
+	chain := testChain(t)
+	roots := x509.NewCertPool()
+	roots.AddCert(chain[2])
+	if err := verifyChain(chain, roots); err != nil {
+		t.Errorf("verifyChain(ordered) error = %v", err)
+	}
+	if err := verifyChain([]*x509.Certificate{chain[0], chain[2], chain[1]}, roots); err == nil || !strings.Contains(err.Error(), "out of order") {
+		t.Errorf("verifyChain(out of order) error = %v", err)
+	}
+	if err := verifyChain(chain[:2], x509.NewCertPool()); err == nil {
+		t.Error("verifyChain() trusted a chain without its root")
+	}
+}
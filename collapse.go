@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// newerCert 判断 a 是否比 b 更新：先比较创建时间，相同时比较 id（APISIX 生成的 id 单调递增），
+// 保证并发的多个调用对保留哪一张得出相同结论，不会互相删除对方
+func newerCert(aCreated time.Time, aID string, bCreated time.Time, bID string) bool {
+	if !aCreated.Equal(bCreated) {
+		return aCreated.After(bCreated)
+	}
+	if len(aID) != len(bID) {
+		return len(aID) > len(bID)
+	}
+	return aID > bID
+}
+
+// collapseDuplicates 在上传成功后重新列出证书，若同一托管标记存在多个托管副本（重试或并发上传导致），
+// 只保留一张，删除其余副本；返回保留的 id 与被删除的 id。
+// 指定了 cert_id（或 idempotent 派生的 id）时保留该 id，否则保留最新的一张。
+// 副本与冲突证书一样受 domain_allowlist 与 protect_in_use 约束，不满足的保留并附带告警
+func collapseDuplicates(a CertBackend, p *bindPlan, cfg map[string]any, certKey string, result map[string]any) (string, []string, error) {
+	certServer, err := a.listCertFromApisix()
+	if err != nil {
+		return certKey, nil, fmt.Errorf("failed to list certs from Apisix: %w", err)
+	}
+	type dup struct {
+		id      string
+		created time.Time
+		snis    []string
+	}
+	var copies []dup
+	for _, cert := range certServer {
+		value, ok := cert["value"].(map[string]any)
+		if !ok || !isManagedCert(value) {
+			continue
+		}
+		id, _ := value["id"].(string)
+		if managedMark(value) != p.Note || id == "" {
+			continue
+		}
+		created, _, _ := certCreated(value)
+		snis, _ := snisFromValue(value)
+		copies = append(copies, dup{id: id, created: created, snis: snis})
+	}
+	if len(copies) <= 1 {
+		return certKey, nil, nil
+	}
+	keep := copies[0]
+	for _, c := range copies[1:] {
+		if newerCert(c.created, c.id, keep.created, keep.id) {
+			keep = c
+		}
+	}
+	if p.ID != "" && certKey == p.ID {
+		for _, c := range copies {
+			if c.id == certKey {
+				keep = c
+			}
+		}
+	}
+	ids := make([]string, 0, len(copies)-1)
+	snisByID := make(map[string][]string)
+	for _, c := range copies {
+		if c.id == keep.id {
+			continue
+		}
+		if len(p.Allowlist) > 0 {
+			if err := p.Allowlist.check(c.snis); err != nil {
+				addWarning(result, warnCollapseFailed, fmt.Sprintf("kept duplicate cert %s: %v", c.id, err))
+				continue
+			}
+		}
+		ids = append(ids, c.id)
+		snisByID[c.id] = c.snis
+	}
+	if protect, _ := cfg["protect_in_use"].(bool); protect && len(ids) > 0 {
+		ids, err = protectInUse(a, ids, snisByID, p.Domain, result)
+		if err != nil {
+			return keep.id, nil, err
+		}
+	}
+	collapsed := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if _, err := a.DeleteCertFromApisix(id); err != nil && !isNotFound(err) {
+			return keep.id, collapsed, fmt.Errorf("failed to delete duplicate cert %s: %w", id, err)
+		}
+		collapsed = append(collapsed, id)
+	}
+	return keep.id, collapsed, nil
+}
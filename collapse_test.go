@@ -0,0 +1,49 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCollapseDuplicates(t *testing.T) {
+	const note = managedPrefix + "dup"
+	tests := []struct {
+		name string
+		// snis2 为副本 2 的 snis，其余副本为 a.test
+		snis2         []any
+		plan          bindPlan
+		cfg           map[string]any
+		certKey       string
+		wantKeep      string
+		wantCollapsed []string
+		wantWarning   bool
+	}{
+		{"newest kept", []any{"a.test"}, bindPlan{}, nil, "1", "3", []string{"1", "2"}, false},
+		{"pinned id kept", []any{"a.test"}, bindPlan{ID: "1"}, nil, "1", "1", []string{"2", "3"}, false},
+		{"outside allowlist", []any{"other.test"}, bindPlan{Allowlist: domainAllowlist{"a.test"}}, nil, "1", "3", []string{"1"}, true},
+		{"in use", []any{"old.test"}, bindPlan{}, map[string]any{"protect_in_use": true}, "1", "3", []string{"1"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newAdminStub(t)
+			stub.routes["r1"] = map[string]any{"id": "r1", "host": "old.test"}
+			stub.putSSL("1", map[string]any{"desc": note, "snis": []any{"a.test"}, "create_time": float64(100)})
+			stub.putSSL("2", map[string]any{"desc": note, "snis": tt.snis2, "create_time": float64(200)})
+			stub.putSSL("3", map[string]any{"desc": note, "snis": []any{"a.test"}, "create_time": float64(300)})
+			p := tt.plan
+			p.Note = note
+			p.Domain = []string{"a.test"}
+			result := map[string]any{}
+			keep, collapsed, err := collapseDuplicates(NewAuth("test-key", stub.URL), &p, tt.cfg, tt.certKey, result)
+			if err != nil {
+				t.Fatalf("collapseDuplicates() error = %v", err)
+			}
+			if keep != tt.wantKeep || !reflect.DeepEqual(collapsed, tt.wantCollapsed) {
+				t.Errorf("collapseDuplicates() = %s, %v, want %s, %v", keep, collapsed, tt.wantKeep, tt.wantCollapsed)
+			}
+			if got := result["warnings"] != nil; got != tt.wantWarning {
+				t.Errorf("warnings = %v, want warning %v", result["warnings"], tt.wantWarning)
+			}
+		})
+	}
+}
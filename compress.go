@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// compressMinSize 为启用 compress_upload 时压缩请求体的最小字节数，更小的请求体压缩收益不大
+const compressMinSize = 8 * 1024
+
+// maxInputSize 为解压后请求内容的上限，避免异常数据耗尽内存
+const maxInputSize = 64 << 20
+
+// gzipMagic 为 gzip 数据的起始字节
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// decompressInput 识别 gzip 压缩的请求内容（按起始字节判断）并解压，未压缩的内容原样返回
+func decompressInput(input []byte) ([]byte, error) {
+	if !bytes.HasPrefix(input, gzipMagic) {
+		return input, nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(input))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress gzip request: %w", err)
+	}
+	return readGzip(zr)
+}
+
+// readGzip 读取解压后的内容，超过 maxInputSize 时报错
+func readGzip(zr *gzip.Reader) ([]byte, error) {
+	defer zr.Close()
+	data, err := io.ReadAll(io.LimitReader(zr, maxInputSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress gzip request: %w", err)
+	}
+	if len(data) > maxInputSize {
+		return nil, fmt.Errorf("decompressed request exceeds %d bytes", maxInputSize)
+	}
+	return data, nil
+}
+
+// gzipBody 压缩请求体，用于 compress_upload
+func gzipBody(body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(body); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net/url"
+)
+
+// consumerUpdater 由支持读写 consumer 的后端实现
+type consumerUpdater interface {
+	getConsumer(username string) (map[string]any, error)
+	putConsumer(consumer map[string]any) error
+}
+
+// getConsumer 读取指定用户名的 consumer，去掉只读的时间字段以便原样写回
+func (a Auth) getConsumer(username string) (map[string]any, error) {
+	res, err := a.ApisixAPI("/consumers/"+url.PathEscape(username), map[string]interface{}{}, "GET")
+	if err != nil {
+		return nil, fmt.Errorf("failed to call Apisix API: %w", err)
+	}
+	consumer, ok := a.unwrapNode(res)["value"].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid response format: consumer value not found")
+	}
+	delete(consumer, "create_time")
+	delete(consumer, "update_time")
+	return consumer, nil
+}
+
+// putConsumer 写入 consumer（PUT /consumers，以 username 为标识）
+func (a Auth) putConsumer(consumer map[string]any) error {
+	if _, err := a.ApisixAPI("/consumers", consumer, "PUT"); err != nil {
+		return fmt.Errorf("failed to call Apisix API: %w", err)
+	}
+	return nil
+}
+
+// hasClientAuth 判断证书是否允许用于客户端认证；未声明 ExtKeyUsage 时视为不限制
+func hasClientAuth(cert *x509.Certificate) bool {
+	if len(cert.ExtKeyUsage) == 0 {
+		return true
+	}
+	for _, usage := range cert.ExtKeyUsage {
+		if usage == x509.ExtKeyUsageClientAuth || usage == x509.ExtKeyUsageAny {
+			return true
+		}
+	}
+	return false
+}
+
+// ConsumerMTLS 将客户端证书写入指定 consumer 的 mTLS 插件配置。
+// 采用读-改-写：只更新插件中的 cert 字段，consumer 的其他插件与字段保持不变
+func ConsumerMTLS(cfg map[string]any) (*Response, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config cannot be nil")
+	}
+	username, ok := cfg["consumer_username"].(string)
+	if !ok || username == "" {
+		return nil, fmt.Errorf("consumer_username is required and must be a string")
+	}
+	certStr, ok := cfg["cert"].(string)
+	if !ok || certStr == "" {
+		return nil, fmt.Errorf("cert is required and must be a string")
+	}
+	// APISIX 没有内置的 consumer mTLS 插件，插件名必须由调用方给出（如自定义插件）
+	plugin, ok := cfg["mtls_plugin"].(string)
+	if !ok || plugin == "" {
+		return nil, fieldErrorf("mtls_plugin", "is required: APISIX has no built-in consumer mTLS plugin, name the plugin that stores the client cert")
+	}
+	leaf, err := parseLeaf(certStr)
+	if err != nil {
+		return nil, err
+	}
+	sha256, err := GetSHA256(certStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get SHA256 of cert: %w", err)
+	}
+	result := map[string]any{
+		"consumer_username": username,
+		"plugin":            plugin,
+		"fingerprint":       sha256,
+		"subject":           leaf.Subject.String(),
+		"warnings":          []string{},
+	}
+	if !hasClientAuth(leaf) {
+		addWarning(result, warnNoClientAuth, "certificate does not allow client authentication (ExtKeyUsage lacks clientAuth)")
+	}
+	minRSABits, err := intParam(cfg, "min_rsa_bits", defaultMinRSABits)
+	if err != nil {
+		return nil, err
+	}
+	allowSHA1, _ := cfg["allow_sha1"].(bool)
+	for _, w := range checkCertPolicy(leaf, minRSABits, allowSHA1) {
+		addWarning(result, warnWeakCert, w)
+	}
+
+	a, err := backendFromParams(cfg)
+	if err != nil {
+		return nil, err
+	}
+	cu, ok := a.(consumerUpdater)
+	if !ok {
+		return nil, fmt.Errorf("consumer_mtls is not supported by this backend")
+	}
+	consumer, err := cu.getConsumer(username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read consumer %s: %w", username, err)
+	}
+	plugins, _ := consumer["plugins"].(map[string]any)
+	if plugins == nil {
+		plugins = map[string]any{}
+	}
+	pluginConf, _ := plugins[plugin].(map[string]any)
+	if pluginConf == nil {
+		pluginConf = map[string]any{}
+	}
+	pluginConf["cert"] = certStr
+	plugins[plugin] = pluginConf
+	consumer["plugins"] = plugins
+	consumer["username"] = username
+
+	if err := cu.putConsumer(consumer); err != nil {
+		return nil, fmt.Errorf("failed to update consumer %s: %w", username, err)
+	}
+	attachDiagnostics(a, result)
+	return &Response{
+		Status:  "success",
+		Message: "Consumer mTLS certificate updated successfully",
+		Result:  result,
+	}, nil
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Dashboard 通过 APISIX Dashboard（manager-api）管理 SSL 对象
+type Dashboard struct {
+	Username      string
+	Password      string
+	ServerAddress string
+	// MaxRetries 为遇到 429 限流时的最大重试次数
+	MaxRetries int
+	// Timeout 为单次 HTTP 请求的超时时间
+	Timeout time.Duration
+	// TLSConfig 为访问 manager-api 使用的 TLS 配置，为 nil 时使用默认配置
+	TLSConfig *tls.Config
+	token     string
+	client    *http.Client
+	ctx       context.Context
+}
+
+// NewDashboard 构造 Dashboard；opts 与 Admin API 后端相同，超时、重试、连接池与 TLS 设置一并生效
+func NewDashboard(username, password, serverAddress string, opts ...AuthOption) *Dashboard {
+	a := NewAuth("", serverAddress, opts...)
+	return &Dashboard{
+		Username:      username,
+		Password:      password,
+		ServerAddress: serverAddress,
+		MaxRetries:    a.MaxRetries,
+		Timeout:       a.Timeout,
+		TLSConfig:     a.TLSConfig,
+		client:        a.httpClient,
+		ctx:           context.Background(),
+	}
+}
+
+// dashboardFromParams 从请求参数中读取 username/password/server_address 并构造 Dashboard
+func dashboardFromParams(cfg map[string]any) (*Dashboard, error) {
+	username, ok := cfg["username"].(string)
+	if !ok || username == "" {
+		return nil, fmt.Errorf("username is required and must be a string")
+	}
+	password, ok := cfg["password"].(string)
+	if !ok || password == "" {
+		return nil, fmt.Errorf("password is required and must be a string")
+	}
+	serverAddress, err := singleServerAddress(cfg)
+	if err != nil {
+		return nil, err
+	}
+	requireHTTPS, _ := cfg["require_https"].(bool)
+	serverAddress, err = normalizeServerAddress(serverAddress, requireHTTPS)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := httpOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	d := NewDashboard(username, password, serverAddress, opts...)
+	d.ctx = runCtx
+	return d, nil
+}
+
+// login 使用用户名密码登录获取 token，仅在首次调用时请求
+func (d *Dashboard) login() error {
+	if d.token != "" {
+		return nil
+	}
+	res, err := d.dashboardAPI("/apisix/admin/user/login", map[string]any{
+		"username": d.Username,
+		"password": d.Password,
+	}, "POST")
+	if err != nil {
+		return fmt.Errorf("dashboard login failed: %w", err)
+	}
+	data, _ := res["data"].(map[string]any)
+	token, ok := data["token"].(string)
+	if !ok || token == "" {
+		return fmt.Errorf("dashboard login failed: token not found")
+	}
+	d.token = token
+	return nil
+}
+
+func (d *Dashboard) uploadCertToApisix(cert, key, note string, domain []string, extra map[string]any) (string, error) {
+	if err := d.login(); err != nil {
+		return "", err
+	}
+	params := map[string]any{
+		"cert": cert,
+		"key":  key,
+		"snis": domain,
+	}
+	// managed_by_field 为 labels 时 note 为空，不写 desc
+	if note != "" {
+		params["desc"] = note
+	}
+	for k, v := range extra {
+		params[k] = v
+	}
+	res, err := d.dashboardAPI("/apisix/admin/ssl", params, "POST")
+	if err != nil {
+		return "", fmt.Errorf("failed to call Dashboard API: %w", err)
+	}
+	data, _ := res["data"].(map[string]any)
+	id, ok := data["id"].(string)
+	if !ok {
+		return "", fmt.Errorf("invalid response format: data.id not found")
+	}
+	return id, nil
+}
+
+// updateCertToApisix 与 Admin API 后端相同采用读-改-写：先读取现有对象，只覆盖插件管理的字段，
+// 其他工具设置的 labels、client、ssl_protocols、status 等字段原样保留
+func (d *Dashboard) updateCertToApisix(certKey, cert, key, note string, domain []string, extra map[string]any) (string, error) {
+	if err := d.login(); err != nil {
+		return "", err
+	}
+	existing, err := d.getSSL(certKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to read cert %s before update: %w", certKey, err)
+	}
+	params := map[string]any{
+		"cert": cert,
+		"key":  key,
+		"snis": domain,
+	}
+	// managed_by_field 为 labels 时 note 为空，不写 desc
+	if note != "" {
+		params["desc"] = note
+	}
+	for k, v := range extra {
+		params[k] = v
+	}
+	_, err = d.dashboardAPI("/apisix/admin/ssl/"+url.PathEscape(certKey), mergeSSL(existing, params), "PUT")
+	if err != nil {
+		return "", fmt.Errorf("failed to call Dashboard API: %w", err)
+	}
+	return certKey, nil
+}
+
+// getSSL 读取指定 id 的 SSL 对象，manager-api 将对象放在 data 中
+func (d *Dashboard) getSSL(certKey string) (map[string]any, error) {
+	res, err := d.dashboardAPI("/apisix/admin/ssl/"+url.PathEscape(certKey), map[string]any{}, "GET")
+	if err != nil {
+		return nil, fmt.Errorf("failed to call Dashboard API: %w", err)
+	}
+	value, ok := res["data"].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid response format: data not found")
+	}
+	return value, nil
+}
+
+func (d *Dashboard) DeleteCertFromApisix(certKey string) (bool, error) {
+	if err := d.login(); err != nil {
+		return false, err
+	}
+	_, err := d.dashboardAPI("/apisix/admin/ssl/"+url.PathEscape(certKey), map[string]any{}, "DELETE")
+	if err != nil {
+		return false, fmt.Errorf("failed to call Dashboard API: %w", err)
+	}
+	return true, nil
+}
+
+// listCertFromApisix 返回与 Admin API 相同的 {key, value} 结构，便于调用方统一处理
+func (d *Dashboard) listCertFromApisix() ([]map[string]any, error) {
+	if err := d.login(); err != nil {
+		return nil, err
+	}
+	res, err := d.dashboardAPI("/apisix/admin/ssl", map[string]any{}, "GET")
+	if err != nil {
+		return nil, fmt.Errorf("failed to call Dashboard API: %w", err)
+	}
+	data, _ := res["data"].(map[string]any)
+	rows, ok := data["rows"].([]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid response format: data.rows not found")
+	}
+	certs := make([]map[string]any, 0, len(rows))
+	for _, row := range rows {
+		value, ok := row.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("invalid response format: cert item is not a map")
+		}
+		id, _ := value["id"].(string)
+		certs = append(certs, map[string]any{
+			"key":   "/apisix/ssls/" + id,
+			"value": value,
+		})
+	}
+	return certs, nil
+}
+
+// dashboardAPI 调用 manager-api，响应统一为 {code, message, data}，code 非 0 视为失败。
+// 与 Admin API 相同，遇到 429 时按 Retry-After 重试至多 MaxRetries 次
+func (d *Dashboard) dashboardAPI(apiPath string, data map[string]interface{}, method string) (map[string]interface{}, error) {
+	method = strings.ToUpper(method)
+	urlStr := strings.TrimRight(d.ServerAddress, "/") + apiPath
+	var body []byte
+	if method != "GET" && method != "DELETE" {
+		var err error
+		body, err = json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	ctx := d.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var resp *http.Response
+	var r []byte
+	for attempt := 0; ; attempt++ {
+		release, err := acquireSlot(ctx, method)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", method, apiPath, err)
+		}
+		resp, r, err = d.send(ctx, method, urlStr, body)
+		release()
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusTooManyRequests || attempt >= d.MaxRetries {
+			break
+		}
+		wait := retryAfter(resp.Header.Get("Retry-After"), time.Now())
+		debugf("%s %s rate limited, retrying in %s (attempt %d/%d)", method, apiPath, wait, attempt+1, d.MaxRetries)
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%s %s aborted while waiting to retry: %w", method, apiPath, ctx.Err())
+		case <-time.After(wait):
+		}
+	}
+	var result map[string]interface{}
+	jsonErr := json.Unmarshal(r, &result)
+	bodyPreview := string(r)
+	if len(bodyPreview) > 500 {
+		bodyPreview = bodyPreview[:500] + "..."
+	}
+	// 与 Admin API 相同以 apiError 返回，调用方可用 isNotFound 判断 404，输出错误时附带 http_status 与 apisix_code
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, newAPIError(resp.StatusCode, bodyPreview, result)
+	}
+	if jsonErr != nil {
+		return nil, fmt.Errorf("dashboard returned HTTP %d with invalid JSON: %s", resp.StatusCode, bodyPreview)
+	}
+	// 缺少 code 时按成功处理，code 无法解析为整数时视为失败
+	if v, ok := result["code"]; ok && v != nil {
+		if code, ok := numericValue(v); !ok || code != 0 {
+			return nil, newAPIError(resp.StatusCode, bodyPreview, result)
+		}
+	}
+	return result, nil
+}
+
+// send 发送一次请求并读取完整响应体
+func (d *Dashboard) send(ctx context.Context, method, urlStr string, body []byte) (*http.Response, []byte, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, urlStr, reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	if body != nil {
+		req.Header.Add("Content-Type", "application/json")
+	}
+	if d.token != "" {
+		req.Header.Add("Authorization", d.token)
+	}
+	client := d.client
+	if client == nil {
+		client = &http.Client{Timeout: defaultTimeout}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	r, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, r, nil
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// dashStub 为内存中的 manager-api：登录返回固定 token，支持 SSL 对象的增删改查，
+// 并记录收到的请求（方法与转义后的路径）
+type dashStub struct {
+	*httptest.Server
+	mu       sync.Mutex
+	ssls     map[string]map[string]any
+	nextID   int
+	requests []string
+	// gone 中的 id 删除时返回 404，模拟已被并发删除
+	gone map[string]bool
+}
+
+func newDashStub(t *testing.T) *dashStub {
+	t.Helper()
+	s := &dashStub{ssls: map[string]map[string]any{}, gone: map[string]bool{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// params 返回访问该 stub 的基础请求参数
+func (s *dashStub) params(extra map[string]any) map[string]any {
+	p := map[string]any{"backend": "dashboard", "username": "admin", "password": "pass", "server_address": s.URL}
+	for k, v := range extra {
+		p[k] = v
+	}
+	return p
+}
+
+func (s *dashStub) putSSL(id string, value map[string]any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value["id"] = id
+	s.ssls[id] = value
+}
+
+func (s *dashStub) getSSL(id string) map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ssls[id]
+}
+
+func (s *dashStub) requested() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+func (s *dashStub) reply(w http.ResponseWriter, status int, body map[string]any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func (s *dashStub) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.EscapedPath())
+	if r.URL.Path == "/apisix/admin/user/login" {
+		var login map[string]any
+		json.NewDecoder(r.Body).Decode(&login)
+		if login["password"] != "pass" {
+			s.reply(w, http.StatusUnauthorized, map[string]any{"code": 10002, "message": "username or password error"})
+			return
+		}
+		s.reply(w, http.StatusOK, map[string]any{"code": 0, "data": map[string]any{"token": "tok"}})
+		return
+	}
+	if r.Header.Get("Authorization") != "tok" {
+		s.reply(w, http.StatusUnauthorized, map[string]any{"code": 10001, "message": "unauthorized"})
+		return
+	}
+	rest, ok := strings.CutPrefix(r.URL.EscapedPath(), "/apisix/admin/ssl")
+	if !ok {
+		s.reply(w, http.StatusNotFound, map[string]any{"code": 10001, "message": "not found"})
+		return
+	}
+	id, _ := url.PathUnescape(strings.TrimPrefix(rest, "/"))
+	var value map[string]any
+	if r.Method == http.MethodPost || r.Method == http.MethodPut {
+		if err := json.NewDecoder(r.Body).Decode(&value); err != nil {
+			s.reply(w, http.StatusBadRequest, map[string]any{"code": 10000, "message": "invalid JSON"})
+			return
+		}
+	}
+	switch {
+	case r.Method == http.MethodGet && id == "":
+		rows := make([]any, 0, len(s.ssls))
+		for _, v := range s.ssls {
+			rows = append(rows, v)
+		}
+		s.reply(w, http.StatusOK, map[string]any{"code": 0, "data": map[string]any{"rows": rows, "total_size": len(rows)}})
+	case r.Method == http.MethodPost:
+		s.nextID++
+		id = strconv.Itoa(s.nextID)
+		value["id"] = id
+		s.ssls[id] = value
+		s.reply(w, http.StatusOK, map[string]any{"code": 0, "data": value})
+	case r.Method == http.MethodDelete && s.gone[id]:
+		delete(s.ssls, id)
+		s.reply(w, http.StatusNotFound, map[string]any{"code": 10001, "message": "data not found"})
+	case s.ssls[id] == nil && r.Method != http.MethodPut:
+		s.reply(w, http.StatusNotFound, map[string]any{"code": 10001, "message": "data not found"})
+	case r.Method == http.MethodGet:
+		s.reply(w, http.StatusOK, map[string]any{"code": 0, "data": s.ssls[id]})
+	case r.Method == http.MethodPut:
+		value["id"] = id
+		s.ssls[id] = value
+		s.reply(w, http.StatusOK, map[string]any{"code": 0, "data": value})
+	case r.Method == http.MethodDelete:
+		delete(s.ssls, id)
+		s.reply(w, http.StatusOK, map[string]any{"code": 0, "data": nil})
+	default:
+		s.reply(w, http.StatusMethodNotAllowed, map[string]any{"code": 10000, "message": "method not allowed"})
+	}
+}
+
+func TestDashboardPathEscaping(t *testing.T) {
+	tests := []struct {
+		id   string
+		want string
+	}{
+		{"1", "/apisix/admin/ssl/1"},
+		{"a/b", "/apisix/admin/ssl/a%2Fb"},
+		{"a b?c", "/apisix/admin/ssl/a%20b%3Fc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			stub := newDashStub(t)
+			stub.putSSL(tt.id, map[string]any{"snis": []any{"a.test"}})
+			d := NewDashboard("admin", "pass", stub.URL)
+			if _, err := d.updateCertToApisix(tt.id, "cert", "key", "allinssl-x", []string{"a.test"}, nil); err != nil {
+				t.Fatalf("updateCertToApisix() error = %v", err)
+			}
+			if ok, err := d.DeleteCertFromApisix(tt.id); err != nil || !ok {
+				t.Fatalf("DeleteCertFromApisix() = %v, %v", ok, err)
+			}
+			got := stub.requested()
+			want := []string{"POST /apisix/admin/user/login", "GET " + tt.want, "PUT " + tt.want, "DELETE " + tt.want}
+			if len(got) != len(want) {
+				t.Fatalf("requests = %v, want %v", got, want)
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Errorf("request %d = %q, want %q", i, got[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+// TestDashboardUpdateKeepsUnmanagedFields 更新时保留其他工具设置的字段，labels 按键合并
+func TestDashboardUpdateKeepsUnmanagedFields(t *testing.T) {
+	stub := newDashStub(t)
+	stub.putSSL("1", map[string]any{
+		"cert":          "old-cert",
+		"snis":          []any{"a.test"},
+		"labels":        map[string]any{"team": "edge"},
+		"client":        map[string]any{"ca": "CA"},
+		"ssl_protocols": []any{"TLSv1.3"},
+		"status":        float64(0),
+	})
+	d := NewDashboard("admin", "pass", stub.URL)
+	extra := map[string]any{"labels": map[string]string{"not-after": "2030-01-01"}}
+	if _, err := d.updateCertToApisix("1", "new-cert", "key", "allinssl-x", []string{"a.test", "b.test"}, extra); err != nil {
+		t.Fatalf("updateCertToApisix() error = %v", err)
+	}
+	stored := stub.getSSL("1")
+	labels, _ := stored["labels"].(map[string]any)
+	if labels["team"] != "edge" || labels["not-after"] != "2030-01-01" {
+		t.Errorf("labels = %v, want team kept and not-after added", labels)
+	}
+	if stored["cert"] != "new-cert" || stored["client"] == nil || stored["ssl_protocols"] == nil || stored["status"] != float64(0) {
+		t.Errorf("stored = %v, want cert replaced and unmanaged fields kept", stored)
+	}
+	if snis, _ := stored["snis"].([]any); len(snis) != 2 {
+		t.Errorf("snis = %v", stored["snis"])
+	}
+}
+
+// TestDashboardLabelsOmitDesc 托管标记存放在 labels 时上传不写 desc，更新保留用户原有备注
+func TestDashboardLabelsOmitDesc(t *testing.T) {
+	stub := newDashStub(t)
+	certPEM, keyPEM := testCert(t, "a.test")
+	resp := runAction(t, "upload_bind", stub.params(map[string]any{"cert": certPEM, "key": keyPEM, "managed_by_field": "labels"}))
+	if resp.Status != "success" {
+		t.Fatalf("upload_bind: %s", resp.Message)
+	}
+	id, _ := resp.Result["cert_id"].(string)
+	stored := stub.getSSL(id)
+	if _, ok := stored["desc"]; ok {
+		t.Errorf("desc = %v, want no desc when managed by labels", stored["desc"])
+	}
+	labels, _ := stored["labels"].(map[string]any)
+	if mark, _ := labels[managedByLabel].(string); !strings.HasPrefix(mark, managedPrefix) {
+		t.Errorf("labels = %v, want %s mark", stored["labels"], managedByLabel)
+	}
+
+	stored["desc"] = "user note"
+	d := NewDashboard("admin", "pass", stub.URL)
+	if _, err := d.updateCertToApisix(id, certPEM, keyPEM, storedDesc(""), []string{"a.test"}, nil); err != nil {
+		t.Fatalf("updateCertToApisix() error = %v", err)
+	}
+	if got := stub.getSSL(id)["desc"]; got != "user note" {
+		t.Errorf("desc after update = %v, want user note kept", got)
+	}
+}
+
+func TestDashboardUpdateReadFailure(t *testing.T) {
+	stub := newDashStub(t)
+	d := NewDashboard("admin", "pass", stub.URL)
+	if _, err := d.updateCertToApisix("missing", "cert", "key", "", []string{"a.test"}, nil); err == nil {
+		t.Fatal("updateCertToApisix() succeeded without an existing object")
+	}
+	for _, r := range stub.requested() {
+		if strings.HasPrefix(r, "PUT ") {
+			t.Errorf("PUT sent although the read failed: %v", stub.requested())
+		}
+	}
+}
+
+func TestDashboardResponseCode(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr bool
+	}{
+		{"number", `{"code": 0, "data": {}}`, false},
+		{"float", `{"code": 0.0, "data": {}}`, false},
+		{"string", `{"code": "0", "data": {}}`, false},
+		{"missing", `{"data": {}}`, false},
+		{"null", `{"code": null, "data": {}}`, false},
+		{"failure number", `{"code": 10001, "message": "denied"}`, true},
+		{"failure string", `{"code": "10001", "message": "denied"}`, true},
+		{"unparsable", `{"code": "oops"}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+			d := NewDashboard("admin", "pass", srv.URL)
+			_, err := d.dashboardAPI("/apisix/admin/ssl", map[string]any{}, "GET")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("dashboardAPI() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDashboardAPIError(t *testing.T) {
+	stub := newDashStub(t)
+	d := NewDashboard("admin", "pass", stub.URL)
+	_, err := d.DeleteCertFromApisix("missing")
+	if !isNotFound(err) {
+		t.Fatalf("DeleteCertFromApisix() error = %v, want a 404 apiError", err)
+	}
+	var ae *apiError
+	if !errors.As(err, &ae) || ae.APISIXCode != "10001" || ae.APISIXMessage != "data not found" {
+		t.Errorf("apiError = %+v", ae)
+	}
+}
+
+// TestDashboardConflictAlreadyGone 冲突证书在删除前已被删除（404）时视为成功
+func TestDashboardConflictAlreadyGone(t *testing.T) {
+	stub := newDashStub(t)
+	certPEM, keyPEM := testCert(t, "a.test")
+	old, oldKey := testCert(t, "a.test")
+	stub.putSSL("9", map[string]any{"cert": old, "key": oldKey, "snis": []any{"a.test"}, "desc": "allinssl-old"})
+	stub.gone["9"] = true
+	resp := runAction(t, "upload_bind", stub.params(map[string]any{"cert": certPEM, "key": keyPEM}))
+	if resp.Status != "success" {
+		t.Fatalf("upload_bind = %s: %s", resp.Status, resp.Message)
+	}
+	if deleted, _ := resp.Result["deleted_ids"].([]any); len(deleted) != 1 || deleted[0] != "9" {
+		t.Errorf("deleted_ids = %v, want [9]", resp.Result["deleted_ids"])
+	}
+}
+
+// TestDashboardErrorDetails 失败响应附带 http_status 与 apisix_code
+func TestDashboardErrorDetails(t *testing.T) {
+	stub := newDashStub(t)
+	resp := runAction(t, "list_certs", stub.params(map[string]any{"password": "wrong"}))
+	if resp.Status != "error" {
+		t.Fatalf("list_certs succeeded with a wrong password")
+	}
+	if resp.Result["http_status"] != float64(http.StatusUnauthorized) || resp.Result["apisix_code"] != "10002" {
+		t.Errorf("result = %v, want http_status 401 and apisix_code 10002", resp.Result)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+)
+
+// DeleteCert 删除单个 SSL 对象，证书由 cert_id 或 fingerprint 指定。
+// 默认只删除由插件托管的证书，force=true 时允许删除其他证书；设置 domain_allowlist 时 snis 必须全部在列表内
+func DeleteCert(cfg map[string]any) (*Response, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config cannot be nil")
+	}
+	certID, err := certIDParam(cfg)
+	if err != nil {
+		return nil, err
+	}
+	fingerprint, _ := cfg["fingerprint"].(string)
+	if certID == "" && fingerprint == "" {
+		return nil, fmt.Errorf("cert_id or fingerprint is required")
+	}
+	allowlist, err := allowlistParam(cfg)
+	if err != nil {
+		return nil, err
+	}
+	force, _ := cfg["force"].(bool)
+
+	a, err := backendFromParams(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var value map[string]any
+	if certID != "" {
+		value, err = findCert(a, certID)
+	} else {
+		value, err = findCertByFingerprint(a, fingerprint)
+	}
+	if err != nil {
+		return nil, err
+	}
+	certID, _ = value["id"].(string)
+	snis, _ := snisFromValue(value)
+	if err := allowlist.check(snis); err != nil {
+		return nil, fmt.Errorf("refusing to delete cert %s: %w", certID, err)
+	}
+	if !force && !isManagedCert(value) {
+		return nil, fmt.Errorf("refusing to delete cert %s: it is not managed by this plugin (%s %q); set force to delete it anyway", certID, managedByField, managedMark(value))
+	}
+	if _, err := a.DeleteCertFromApisix(certID); err != nil {
+		return nil, fmt.Errorf("failed to delete cert %s: %w", certID, err)
+	}
+	result := map[string]any{
+		"cert_id": certID,
+		"snis":    snis,
+		"deleted": []string{certID},
+	}
+	attachDiagnostics(a, result)
+	return &Response{
+		Status:  "success",
+		Message: "Certificate deleted successfully",
+		Result:  result,
+	}, nil
+}
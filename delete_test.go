@@ -0,0 +1,59 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDeleteCert(t *testing.T) {
+	certPEM, keyPEM := testCert(t, "a.test")
+	sha, err := GetSHA256(certPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		value   map[string]any
+		params  map[string]any
+		wantErr string
+	}{
+		{"managed", map[string]any{"desc": managedPrefix + sha}, map[string]any{"cert_id": "1"}, ""},
+		{"managed by label", map[string]any{"labels": map[string]any{managedByLabel: managedPrefix + sha}}, map[string]any{"cert_id": "1", "managed_by_field": "labels"}, ""},
+		{"by fingerprint", map[string]any{"desc": managedPrefix + sha}, map[string]any{"fingerprint": sha}, ""},
+		{"unmanaged", map[string]any{"desc": "hand made"}, map[string]any{"cert_id": "1"}, "not managed by this plugin"},
+		{"unmanaged forced", map[string]any{"desc": "hand made"}, map[string]any{"cert_id": "1", "force": true}, ""},
+		{"outside allowlist", map[string]any{"desc": managedPrefix + sha}, map[string]any{"cert_id": "1", "domain_allowlist": []any{"b.test"}}, "domain_allowlist"},
+		{"inside allowlist", map[string]any{"desc": managedPrefix + sha}, map[string]any{"cert_id": "1", "domain_allowlist": []any{"a.test"}}, ""},
+		{"missing", map[string]any{"desc": managedPrefix + sha}, map[string]any{"cert_id": "2"}, "not found"},
+		{"no selector", map[string]any{"desc": managedPrefix + sha}, map[string]any{}, "cert_id or fingerprint is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newAdminStub(t)
+			value := map[string]any{"cert": certPEM, "key": keyPEM, "snis": []any{"a.test"}}
+			for k, v := range tt.value {
+				value[k] = v
+			}
+			stub.putSSL("1", value)
+			resp := runAction(t, "delete_cert", stub.params(tt.params))
+			if tt.wantErr != "" {
+				if resp.Status != "error" || !strings.Contains(resp.Message, tt.wantErr) {
+					t.Fatalf("delete_cert = %s %q, want error containing %q", resp.Status, resp.Message, tt.wantErr)
+				}
+				if len(stub.sslIDs()) != 1 {
+					t.Error("cert deleted despite the error")
+				}
+				return
+			}
+			if resp.Status != "success" {
+				t.Fatalf("delete_cert: %s", resp.Message)
+			}
+			if deleted, _ := resp.Result["deleted"].([]any); len(deleted) != 1 || deleted[0] != "1" {
+				t.Errorf("deleted = %v, want [1]", resp.Result["deleted"])
+			}
+			if ids := stub.sslIDs(); len(ids) != 0 {
+				t.Errorf("ssl objects left: %v", ids)
+			}
+		})
+	}
+}
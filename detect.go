@@ -0,0 +1,38 @@
+package main
+
+// detectAPI 发起一次只读的 /ssls 请求，根据列表响应结构推断 Admin API 版本：
+// v3 返回 {"list": [...]}，v2 返回 {"node": {"nodes": ...}}；
+// 已有证书只使用标量 sni 字段时，同时推断网关需要旧版 sni 写法。
+// 探测结果保存在 Auth 上，本次调用的后续请求直接复用
+func (a *Auth) detectAPI(detectSNI bool) error {
+	a.APIVersion = ""
+	res, err := a.ApisixAPI("/ssls?page=1&page_size=10", map[string]interface{}{}, "GET")
+	if err != nil {
+		a.APIVersion = defaultAPIVersion
+		return err
+	}
+	var items []any
+	if node, ok := res["node"].(map[string]any); ok {
+		a.APIVersion = "v2"
+		items, _ = node["nodes"].([]any)
+	} else {
+		a.APIVersion = "v3"
+		items, _ = res["list"].([]any)
+	}
+	a.APIDetected = true
+	if !detectSNI {
+		return nil
+	}
+	scalar, array := false, false
+	for _, item := range items {
+		m, _ := item.(map[string]any)
+		value, _ := m["value"].(map[string]any)
+		if _, ok := value["snis"].([]any); ok {
+			array = true
+		} else if _, ok := value["sni"].(string); ok {
+			scalar = true
+		}
+	}
+	a.LegacySNI = scalar && !array
+	return nil
+}
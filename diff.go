@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+)
+
+// desiredCert 描述期望部署到网关上的一张证书
+type desiredCert struct {
+	Cert   string
+	Key    string
+	Domain []string
+	SHA256 string
+}
+
+// parseDesiredCerts 解析 entries 参数：[{cert, key, domain}, ...]
+func parseDesiredCerts(v any) ([]desiredCert, error) {
+	entries, ok := v.([]any)
+	if !ok || len(entries) == 0 {
+		return nil, fmt.Errorf("entries is required and must be a non-empty array")
+	}
+	desired := make([]desiredCert, 0, len(entries))
+	for i, item := range entries {
+		entry, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("entry at index %d is not an object", i)
+		}
+		certStr, ok := entry["cert"].(string)
+		if !ok || certStr == "" {
+			return nil, fmt.Errorf("entry at index %d: cert is required and must be a string", i)
+		}
+		keyStr, _ := entry["key"].(string)
+		domain, err := parseDomains(entry["domain"])
+		if err != nil {
+			return nil, renameField(err, "domain", fmt.Sprintf("entries[%d].domain", i))
+		}
+		sha256, err := GetSHA256(certStr)
+		if err != nil {
+			return nil, fmt.Errorf("entry at index %d: failed to get SHA256 of cert: %w", i, err)
+		}
+		desired = append(desired, desiredCert{Cert: certStr, Key: keyStr, Domain: domain, SHA256: sha256})
+	}
+	return desired, nil
+}
+
+// Diff 对比期望状态与网关当前托管证书，只读，不做任何修改
+// 返回 to_create / to_update / to_delete 三类变更，以及无需变更的 unchanged
+func Diff(cfg map[string]any) (*Response, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config cannot be nil")
+	}
+	a, err := backendFromParams(cfg)
+	if err != nil {
+		return nil, err
+	}
+	desired, err := parseDesiredCerts(cfg["entries"])
+	if err != nil {
+		return nil, err
+	}
+	certServer, err := a.listCertFromApisix()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list certs from Apisix: %w", err)
+	}
+
+	type managedCert struct {
+		id      string
+		desc    string
+		snis    []string
+		claimed bool
+	}
+	managed := make([]*managedCert, 0, len(certServer))
+	for _, cert := range certServer {
+		value, ok := cert["value"].(map[string]any)
+		if !ok || !isManagedCert(value) {
+			continue
+		}
+		id, _ := value["id"].(string)
+		snis, _ := snisFromValue(value)
+		managed = append(managed, &managedCert{id: id, desc: managedMark(value), snis: snis})
+	}
+
+	toCreate := make([]map[string]any, 0)
+	toUpdate := make([]map[string]any, 0)
+	unchanged := make([]map[string]any, 0)
+	for _, d := range desired {
+		note := managedPrefix + d.SHA256
+		var match *managedCert
+		reason := ""
+		// 优先按指纹匹配，其次按 snis 完全一致匹配
+		for _, m := range managed {
+			if !m.claimed && m.desc == note {
+				match = m
+				if compareSliceRelation(m.snis, d.Domain) != 2 {
+					reason = "snis mismatch"
+				}
+				break
+			}
+		}
+		if match == nil {
+			for _, m := range managed {
+				if !m.claimed && compareSliceRelation(m.snis, d.Domain) == 2 {
+					match = m
+					reason = "fingerprint mismatch"
+					break
+				}
+			}
+		}
+		item := map[string]any{
+			"fingerprint": d.SHA256,
+			"domain":      d.Domain,
+		}
+		if match == nil {
+			toCreate = append(toCreate, item)
+			continue
+		}
+		match.claimed = true
+		item["cert_id"] = match.id
+		item["current_snis"] = match.snis
+		if reason == "" {
+			unchanged = append(unchanged, item)
+			continue
+		}
+		item["reason"] = reason
+		toUpdate = append(toUpdate, item)
+	}
+	toDelete := make([]map[string]any, 0)
+	for _, m := range managed {
+		if m.claimed {
+			continue
+		}
+		toDelete = append(toDelete, map[string]any{
+			"cert_id": m.id,
+			"desc":    m.desc,
+			"snis":    m.snis,
+		})
+	}
+
+	result := map[string]any{
+		"to_create": toCreate,
+		"to_update": toUpdate,
+		"to_delete": toDelete,
+		"unchanged": unchanged,
+	}
+	attachDiagnostics(a, result)
+	return &Response{
+		Status:  "success",
+		Message: "Diff computed successfully",
+		Result:  result,
+	}, nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+// configEcho 为 config_echo 时本次请求解析出的有效配置，输出响应时写入 result["effective_config"]；
+// 每次请求前重置
+var configEcho map[string]any
+
+// effectiveConfig 返回合并请求参数、环境变量与默认值后实际使用的配置，敏感信息脱敏。
+// envApplied 为由环境变量补全的参数及对应的变量名
+func effectiveConfig(cfg map[string]any, envApplied map[string]string) map[string]any {
+	backend, _ := cfg["backend"].(string)
+	if backend == "" {
+		backend = "admin_api"
+	}
+	mode, _ := cfg["mode"].(string)
+	if mode == "" {
+		mode = "admin_api"
+	}
+	echo := map[string]any{
+		"backend":          backend,
+		"mode":             mode,
+		"managed_by_field": managedByField,
+		"strict":           strictMode,
+		"output_format":    outputFormat,
+		"max_concurrency":  cap(writeSlots),
+		"delete":           deleteSettings(cfg),
+	}
+	if len(envApplied) > 0 {
+		echo["from_env"] = envApplied
+	}
+	switch backend {
+	case "admin_api":
+		// api_version=auto 的探测需要访问网关，这里不发起请求，只报告 auto
+		probeCfg := cfg
+		autoVersion := false
+		if v, _ := cfg["api_version"].(string); strings.EqualFold(v, "auto") {
+			autoVersion = true
+			probeCfg = make(map[string]any, len(cfg))
+			for k, v := range cfg {
+				probeCfg[k] = v
+			}
+			delete(probeCfg, "api_version")
+		}
+		a, err := authFromParams(probeCfg)
+		if err != nil {
+			echo["error"] = err.Error()
+			return echo
+		}
+		echo["server_address"] = a.ServerAddress
+		echo["admin_prefix"] = a.AdminPrefix
+		echo["admin_key"] = redact(a.AdminKey)
+		echo["admin_key_source"] = a.KeySource
+		echo["auth_scheme"] = "x-api-key"
+		if a.AuthScheme != "" {
+			echo["auth_scheme"] = a.AuthScheme
+		}
+		echo["api_version"] = a.APIVersion
+		if autoVersion {
+			echo["api_version"] = "auto"
+		}
+		echo["legacy_sni"] = a.LegacySNI
+		echo["body_format"] = "json"
+		if a.BodyFormat != "" {
+			echo["body_format"] = a.BodyFormat
+		}
+		echo["compress_upload"] = a.CompressUpload
+		echo["timeout_seconds"] = a.Timeout.Seconds()
+		echo["max_retries"] = a.MaxRetries
+		echo["max_idle_conns"] = a.MaxIdleConns
+		echo["idle_conn_timeout_ms"] = a.IdleConnTimeout.Milliseconds()
+		if len(a.OpTimeouts) > 0 {
+			opTimeouts := make(map[string]int64, len(a.OpTimeouts))
+			for method, d := range a.OpTimeouts {
+				opTimeouts[method] = d.Milliseconds()
+			}
+			echo["op_timeout_ms"] = opTimeouts
+		}
+		if a.GatewayGroup != "" {
+			echo["gateway_group"] = a.GatewayGroup
+			echo["gateway_group_mode"] = "header"
+			if a.GatewayGroupMode != "" {
+				echo["gateway_group_mode"] = a.GatewayGroupMode
+			}
+		}
+		if len(a.IDPaths) > 0 {
+			echo["id_path"] = a.IDPaths
+		}
+		if a.TTLSeconds > 0 {
+			echo["ttl_seconds"] = a.TTLSeconds
+		}
+		if a.BasicUser != "" {
+			echo["basic_user"] = a.BasicUser
+			echo["basic_pass"] = redact(a.BasicPass)
+		}
+		if a.TLSConfig != nil {
+			echo["insecure_skip_verify"] = a.TLSConfig.InsecureSkipVerify
+		}
+	case "dashboard":
+		d, err := dashboardFromParams(cfg)
+		if err != nil {
+			echo["error"] = err.Error()
+			return echo
+		}
+		echo["server_address"] = d.ServerAddress
+		echo["username"] = d.Username
+		echo["timeout_seconds"] = d.Timeout.Seconds()
+		echo["max_retries"] = d.MaxRetries
+		if d.TLSConfig != nil {
+			echo["insecure_skip_verify"] = d.TLSConfig.InsecureSkipVerify
+		}
+	case "etcd":
+		e, err := etcdFromParams(cfg)
+		if err != nil {
+			echo["error"] = err.Error()
+			return echo
+		}
+		echo["etcd_endpoints"] = e.Endpoints
+		echo["etcd_prefix"] = e.Prefix
+		if e.Username != "" {
+			echo["etcd_username"] = e.Username
+			echo["etcd_password"] = redact(e.Password)
+		}
+	}
+	return echo
+}
+
+// deleteSettings 汇总 upload_bind 处理冲突证书的方式：
+// none（no_delete 或 force，不删除）、best_effort（逐个删除，失败时返回 partial）、
+// rollback（默认，任一删除失败即回滚）
+func deleteSettings(cfg map[string]any) map[string]any {
+	noDelete, _ := cfg["no_delete"].(bool)
+	force, _ := cfg["force"].(bool)
+	bestEffort, _ := cfg["best_effort_delete"].(bool)
+	policy := "rollback"
+	switch {
+	case noDelete || force:
+		policy = "none"
+	case bestEffort:
+		policy = "best_effort"
+	}
+	collapse, ok := cfg["collapse_duplicates"].(bool)
+	if !ok {
+		collapse = !force
+	}
+	settings := map[string]any{
+		"policy":              policy,
+		"collapse_duplicates": collapse && !noDelete,
+	}
+	for _, name := range []string{"verify_before_delete", "protect_in_use", "restore_on_rollback"} {
+		v, _ := cfg[name].(bool)
+		settings[name] = v
+	}
+	if v, _ := settings["verify_before_delete"].(bool); v {
+		timeout, err := intParam(cfg, "verify_timeout_ms", int(defaultVerifyTimeout/time.Millisecond))
+		if err == nil {
+			settings["verify_timeout_ms"] = timeout
+		}
+	}
+	return settings
+}
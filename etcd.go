@@ -0,0 +1,216 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// EtcdClient 通过 etcd v3 的 HTTP/JSON 网关直接读写 APISIX 配置，
+// 用于未暴露 Admin API 的部署
+type EtcdClient struct {
+	Endpoints []string
+	Prefix    string
+	Username  string
+	Password  string
+	client    *http.Client
+	token     string
+}
+
+// etcdFromParams 从请求参数构造 EtcdClient
+func etcdFromParams(cfg map[string]any) (*EtcdClient, error) {
+	var endpoints []string
+	switch v := cfg["etcd_endpoints"].(type) {
+	case string:
+		for _, e := range strings.Split(v, ",") {
+			if e = strings.TrimSpace(e); e != "" {
+				endpoints = append(endpoints, e)
+			}
+		}
+	case []any:
+		for i, e := range v {
+			s, ok := e.(string)
+			if !ok {
+				return nil, fmt.Errorf("etcd_endpoints element at index %d is not a string", i)
+			}
+			endpoints = append(endpoints, s)
+		}
+	}
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("etcd_endpoints is required")
+	}
+	prefix, _ := cfg["etcd_prefix"].(string)
+	if prefix == "" {
+		prefix = "/apisix"
+	}
+	username, _ := cfg["etcd_username"].(string)
+	password, _ := cfg["etcd_password"].(string)
+
+	tlsConfig := &tls.Config{}
+	if skip, ok := cfg["etcd_insecure_skip_verify"].(bool); ok {
+		tlsConfig.InsecureSkipVerify = skip
+	}
+	if caFile, ok := cfg["etcd_ca_file"].(string); ok && caFile != "" {
+		caPEM, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read etcd_ca_file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("etcd_ca_file contains no valid certificates")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	certFile, _ := cfg["etcd_cert_file"].(string)
+	keyFile, _ := cfg["etcd_key_file"].(string)
+	if certFile != "" || keyFile != "" {
+		clientCert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load etcd client cert: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{clientCert}
+	}
+
+	return &EtcdClient{
+		Endpoints: endpoints,
+		Prefix:    strings.TrimRight(prefix, "/"),
+		Username:  username,
+		Password:  password,
+		client: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		},
+	}, nil
+}
+
+// call 依次尝试各个 endpoint，直到某个 endpoint 返回成功
+func (e *EtcdClient) call(apiPath string, data map[string]any) (map[string]any, error) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var lastErr error
+	for _, endpoint := range e.Endpoints {
+		req, err := http.NewRequest("POST", strings.TrimRight(endpoint, "/")+apiPath, strings.NewReader(string(body)))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		req.Header.Add("Content-Type", "application/json")
+		if e.token != "" {
+			req.Header.Add("Authorization", e.token)
+		}
+		resp, err := e.client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		r, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			lastErr = fmt.Errorf("etcd %s returned HTTP %d: %s", endpoint, resp.StatusCode, string(r))
+			continue
+		}
+		var result map[string]any
+		if err := json.Unmarshal(r, &result); err != nil {
+			return nil, fmt.Errorf("etcd response is not valid JSON: %w", err)
+		}
+		return result, nil
+	}
+	return nil, lastErr
+}
+
+// authenticate 配置了用户名时获取 etcd 访问 token
+func (e *EtcdClient) authenticate() error {
+	if e.Username == "" || e.token != "" {
+		return nil
+	}
+	res, err := e.call("/v3/auth/authenticate", map[string]any{
+		"name":     e.Username,
+		"password": e.Password,
+	})
+	if err != nil {
+		return fmt.Errorf("etcd authenticate failed: %w", err)
+	}
+	token, ok := res["token"].(string)
+	if !ok || token == "" {
+		return fmt.Errorf("etcd authenticate failed: token not found")
+	}
+	e.token = token
+	return nil
+}
+
+// putSSL 将 SSL 对象写入 <prefix>/ssls/<id>，结构与 Admin API 创建的对象保持一致；note 为空时不写 desc
+func (e *EtcdClient) putSSL(id, cert, key, note string, domain []string, extra map[string]any) (string, error) {
+	if err := e.authenticate(); err != nil {
+		return "", err
+	}
+	now := time.Now().Unix()
+	ssl := map[string]any{
+		"id":          id,
+		"cert":        cert,
+		"key":         key,
+		"snis":        domain,
+		"create_time": now,
+		"update_time": now,
+	}
+	if note != "" {
+		ssl["desc"] = note
+	}
+	for k, v := range extra {
+		ssl[k] = v
+	}
+	value, err := json.Marshal(ssl)
+	if err != nil {
+		return "", err
+	}
+	etcdKey := e.Prefix + "/ssls/" + id
+	release, err := acquireWrite(runCtx)
+	if err != nil {
+		return "", fmt.Errorf("failed to put %s to etcd: %w", etcdKey, err)
+	}
+	_, err = e.call("/v3/kv/put", map[string]any{
+		"key":   base64.StdEncoding.EncodeToString([]byte(etcdKey)),
+		"value": base64.StdEncoding.EncodeToString(value),
+	})
+	release()
+	if err != nil {
+		return "", fmt.Errorf("failed to put %s to etcd: %w", etcdKey, err)
+	}
+	return id, nil
+}
+
+// uploadEtcd 直接写入 etcd 完成部署；id 由证书指纹决定，重复写入是幂等的
+func uploadEtcd(cfg map[string]any, id, cert, key, note string, domain []string, extra map[string]any, result map[string]interface{}) (*Response, error) {
+	e, err := etcdFromParams(cfg)
+	if err != nil {
+		return nil, err
+	}
+	certKey, err := e.putSSL(id, cert, key, note, domain, extra)
+	if err != nil {
+		return nil, err
+	}
+	result["message"] = "绑定成功"
+	result["action"] = "created"
+	result["changed"] = true
+	result["cert_id"] = certKey
+	result["snis"] = domain
+	// 直接写入 etcd 时不检查也不删除冲突证书
+	result["deleted_ids"] = []string{}
+	return &Response{
+		Status:  "success",
+		Message: "Certificate uploaded and bound successfully",
+		Result:  result,
+	}, nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// 默认检查 30 天内到期的证书
+const defaultWithinDays = 30
+
+// certNotAfter 获取 SSL 对象的到期时间：优先解析证书内容，APISIX 未返回证书时退回读取 not-after 标签
+func certNotAfter(value map[string]any) (time.Time, string, bool) {
+	if certStr, ok := value["cert"].(string); ok && certStr != "" {
+		if leaf, err := parseLeaf(certStr); err == nil {
+			return leaf.NotAfter, "cert", true
+		}
+	}
+	if labels, ok := value["labels"].(map[string]any); ok {
+		if s, ok := labels["not-after"].(string); ok {
+			if t, err := time.Parse("2006-01-02", s); err == nil {
+				return t, "label", true
+			}
+		}
+	}
+	return time.Time{}, "", false
+}
+
+// Count 返回 SSL 对象总数、托管数量以及 within_days 天内到期的托管证书数量
+func Count(cfg map[string]any) (*Response, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config cannot be nil")
+	}
+	a, err := backendFromParams(cfg)
+	if err != nil {
+		return nil, err
+	}
+	withinDays, err := nonNegativeIntParam(cfg, "within_days", defaultWithinDays)
+	if err != nil {
+		return nil, err
+	}
+	certServer, err := a.listCertFromApisix()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list certs from Apisix: %w", err)
+	}
+
+	deadline := time.Now().AddDate(0, 0, withinDays)
+	managed, expiring := 0, 0
+	for _, cert := range certServer {
+		value, ok := cert["value"].(map[string]any)
+		if !ok || !isManagedCert(value) {
+			continue
+		}
+		managed++
+		if notAfter, _, ok := certNotAfter(value); ok && !notAfter.After(deadline) {
+			expiring++
+		}
+	}
+
+	result := map[string]any{
+		"total":       len(certServer),
+		"managed":     managed,
+		"expiring":    expiring,
+		"within_days": withinDays,
+	}
+	attachDiagnostics(a, result)
+	return &Response{
+		Status:  "success",
+		Message: "Certificates counted successfully",
+		Result:  result,
+	}, nil
+}
+
+// ListExpiring 列出 within_days 天内到期的托管证书，按到期时间升序排列
+func ListExpiring(cfg map[string]any) (*Response, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config cannot be nil")
+	}
+	a, err := backendFromParams(cfg)
+	if err != nil {
+		return nil, err
+	}
+	withinDays, err := nonNegativeIntParam(cfg, "within_days", defaultWithinDays)
+	if err != nil {
+		return nil, err
+	}
+	certServer, err := a.listCertFromApisix()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list certs from Apisix: %w", err)
+	}
+
+	now := time.Now()
+	deadline := now.AddDate(0, 0, withinDays)
+	type expiring struct {
+		notAfter time.Time
+		item     map[string]any
+	}
+	found := make([]expiring, 0)
+	unknown := make([]string, 0)
+	for _, cert := range certServer {
+		value, ok := cert["value"].(map[string]any)
+		if !ok || !isManagedCert(value) {
+			continue
+		}
+		id, _ := value["id"].(string)
+		notAfter, source, ok := certNotAfter(value)
+		if !ok {
+			unknown = append(unknown, id)
+			continue
+		}
+		if notAfter.After(deadline) {
+			continue
+		}
+		snis, _ := snisFromValue(value)
+		found = append(found, expiring{
+			notAfter: notAfter,
+			item: map[string]any{
+				"cert_id":   id,
+				"snis":      snis,
+				"desc":      value["desc"],
+				"not_after": notAfter.UTC().Format(time.RFC3339),
+				"days_left": int(notAfter.Sub(now).Hours() / 24),
+				"source":    source,
+			},
+		})
+	}
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].notAfter.Before(found[j].notAfter)
+	})
+	certs := make([]map[string]any, 0, len(found))
+	for _, e := range found {
+		certs = append(certs, e.item)
+	}
+
+	result := map[string]any{
+		"within_days": withinDays,
+		"count":       len(certs),
+		"certs":       certs,
+	}
+	if len(unknown) > 0 {
+		// 既无证书内容也无 not-after 标签，无法判断到期时间
+		result["unknown"] = unknown
+	}
+	attachDiagnostics(a, result)
+	return &Response{
+		Status:  "success",
+		Message: "Expiring certificates listed successfully",
+		Result:  result,
+	}, nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// exportEntry 将 SSL 对象整理为导出结果：证书（含链）按 PEM 原样返回，私钥不导出
+func exportEntry(value map[string]any) (map[string]any, error) {
+	id, _ := value["id"].(string)
+	certStr, _ := value["cert"].(string)
+	if strings.TrimSpace(certStr) == "" {
+		return nil, fmt.Errorf("cert %s has no certificate content in the API response", id)
+	}
+	chain, err := parseChain(certStr)
+	if err != nil {
+		return nil, fmt.Errorf("cert %s: %w", id, err)
+	}
+	snis, _ := snisFromValue(value)
+	entry := map[string]any{
+		"cert_id":     id,
+		"snis":        snis,
+		"desc":        value["desc"],
+		"pem":         encodeChain(chain),
+		"chain_count": len(chain),
+		// APISIX 不返回私钥（或只返回加密后的私钥），导出结果不包含私钥
+		"key_included": false,
+	}
+	if sha256, err := GetSHA256(certStr); err == nil {
+		entry["fingerprint"] = sha256
+	}
+	if alt, ok := value["certs"].([]any); ok && len(alt) > 0 {
+		altPEM := make([]string, 0, len(alt))
+		for _, c := range alt {
+			if s, ok := c.(string); ok {
+				altPEM = append(altPEM, s)
+			}
+		}
+		entry["alt_certs"] = altPEM
+	}
+	return entry, nil
+}
+
+// ExportPEM 按 cert_id 或 domain 导出网关上存储的证书 PEM，用于迁移与审计；
+// 按 domain 查找时返回 snis 包含该域名的全部证书
+func ExportPEM(cfg map[string]any) (*Response, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config cannot be nil")
+	}
+	certID, _ := cfg["cert_id"].(string)
+	domain, _ := cfg["domain"].(string)
+	if certID == "" && domain == "" {
+		return nil, fmt.Errorf("cert_id or domain is required")
+	}
+	a, err := backendFromParams(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]map[string]any, 0)
+	if certID != "" {
+		value, err := findCert(a, certID)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	} else {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		certServer, err := a.listCertFromApisix()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list certs from Apisix: %w", err)
+		}
+		for _, cert := range certServer {
+			value, ok := cert["value"].(map[string]any)
+			if !ok {
+				continue
+			}
+			snis, _ := snisFromValue(value)
+			for _, sni := range snis {
+				if strings.ToLower(sni) == domain {
+					values = append(values, value)
+					break
+				}
+			}
+		}
+		if len(values) == 0 {
+			return nil, fmt.Errorf("no cert found for domain %s", domain)
+		}
+	}
+
+	certs := make([]map[string]any, 0, len(values))
+	for _, value := range values {
+		entry, err := exportEntry(value)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, entry)
+	}
+	result := map[string]any{
+		"certs": certs,
+		"count": len(certs),
+		"note":  "private keys are not exported; APISIX does not return them in plain text",
+	}
+	attachDiagnostics(a, result)
+	return &Response{
+		Status:  "success",
+		Message: "Certificates exported successfully",
+		Result:  result,
+	}, nil
+}
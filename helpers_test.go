@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testCert 生成自签证书，返回 PEM 编码的证书与私钥；names 的第一个作为 CN
+func testCert(t *testing.T, names ...string) (string, string) {
+	t.Helper()
+	return testCertValid(t, time.Now().Add(-time.Hour), time.Now().Add(90*24*time.Hour), names...)
+}
+
+// testCertValid 同 testCert，可指定有效期
+func testCertValid(t *testing.T, notBefore, notAfter time.Time, names ...string) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: names[0]},
+		DNSNames:     names,
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return string(certPEM), string(keyPEM)
+}
+
+// adminStub 为内存中的 APISIX Admin API（3.x 响应结构），支持 ssls、routes 与 consumers 的增删改查
+type adminStub struct {
+	*httptest.Server
+	mu        sync.Mutex
+	ssls      map[string]map[string]any
+	routes    map[string]map[string]any
+	consumers map[string]map[string]any
+	nextID    int
+	// failDelete 中的 id 删除时返回 500
+	failDelete map[string]bool
+	// rateLimit 为接下来需要返回 429 的请求数
+	rateLimit int
+	requests  []string
+}
+
+func newAdminStub(t *testing.T) *adminStub {
+	t.Helper()
+	s := &adminStub{
+		ssls:       map[string]map[string]any{},
+		routes:     map[string]map[string]any{},
+		consumers:  map[string]map[string]any{},
+		failDelete: map[string]bool{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// params 返回访问该 stub 的基础请求参数
+func (s *adminStub) params(extra map[string]any) map[string]any {
+	p := map[string]any{"admin_key": "test-key", "server_address": s.URL}
+	for k, v := range extra {
+		p[k] = v
+	}
+	return p
+}
+
+// putSSL 直接向 stub 写入 SSL 对象
+func (s *adminStub) putSSL(id string, value map[string]any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value["id"] = id
+	s.ssls[id] = value
+}
+
+// sslIDs 返回当前 SSL 对象 id，按数字顺序排列
+func (s *adminStub) sslIDs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := make([]string, 0, len(s.ssls))
+	for id := range s.ssls {
+		ids = append(ids, id)
+	}
+	sortIDs(ids)
+	return ids
+}
+
+// requested 判断是否收到过指定 "METHOD path" 的请求
+func (s *adminStub) requested(methodPath string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.requests {
+		if r == methodPath {
+			return true
+		}
+	}
+	return false
+}
+
+func sortIDs(ids []string) {
+	sort.Slice(ids, func(i, j int) bool {
+		a, errA := strconv.Atoi(ids[i])
+		b, errB := strconv.Atoi(ids[j])
+		if errA == nil && errB == nil {
+			return a < b
+		}
+		return ids[i] < ids[j]
+	})
+}
+
+func (s *adminStub) reply(w http.ResponseWriter, code int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(body)
+}
+
+func (s *adminStub) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// 按转义后的路径拆分，确认 id 中的特殊字符经过了 url.PathEscape
+	path := strings.TrimPrefix(r.URL.EscapedPath(), adminPathPrefix)
+	s.requests = append(s.requests, r.Method+" "+path)
+	if r.Header.Get("X-API-KEY") != "test-key" {
+		s.reply(w, http.StatusUnauthorized, map[string]any{"message": "failed to check token"})
+		return
+	}
+	if s.rateLimit > 0 {
+		s.rateLimit--
+		w.Header().Set("Retry-After", "0")
+		s.reply(w, http.StatusTooManyRequests, map[string]any{"error_msg": "too many requests"})
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	var store map[string]map[string]any
+	switch parts[0] {
+	case "ssls":
+		store = s.ssls
+	case "routes":
+		store = s.routes
+	case "consumers":
+		store = s.consumers
+	default:
+		s.reply(w, http.StatusNotFound, map[string]any{"error_msg": "404 Route Not Found"})
+		return
+	}
+	kind := parts[0]
+	id := ""
+	if len(parts) > 2 {
+		s.reply(w, http.StatusNotFound, map[string]any{"error_msg": "404 Route Not Found"})
+		return
+	}
+	if len(parts) > 1 {
+		id, _ = url.PathUnescape(parts[1])
+	}
+	var value map[string]any
+	if len(body) > 0 {
+		if err := json.NewDecoder(bytes.NewReader(body)).Decode(&value); err != nil {
+			s.reply(w, http.StatusBadRequest, map[string]any{"error_msg": "invalid JSON"})
+			return
+		}
+	}
+	item := func(id string, v map[string]any) map[string]any {
+		return map[string]any{"key": "/apisix/" + kind + "/" + id, "value": v}
+	}
+	switch {
+	case r.Method == http.MethodGet && id == "":
+		ids := make([]string, 0, len(store))
+		for k := range store {
+			ids = append(ids, k)
+		}
+		sortIDs(ids)
+		list := make([]any, 0, len(ids))
+		for _, k := range ids {
+			list = append(list, item(k, store[k]))
+		}
+		s.reply(w, http.StatusOK, map[string]any{"list": list, "total": len(list)})
+	case r.Method == http.MethodGet:
+		v, ok := store[id]
+		if !ok {
+			s.reply(w, http.StatusNotFound, map[string]any{"message": "Key not found"})
+			return
+		}
+		s.reply(w, http.StatusOK, item(id, v))
+	case r.Method == http.MethodPost:
+		s.nextID++
+		id = strconv.Itoa(s.nextID)
+		for store[id] != nil {
+			s.nextID++
+			id = strconv.Itoa(s.nextID)
+		}
+		value["id"] = id
+		store[id] = value
+		s.reply(w, http.StatusCreated, item(id, value))
+	case r.Method == http.MethodPut:
+		if kind == "consumers" {
+			id, _ = value["username"].(string)
+		} else {
+			value["id"] = id
+		}
+		store[id] = value
+		s.reply(w, http.StatusOK, item(id, value))
+	case r.Method == http.MethodPatch:
+		merged := map[string]any{}
+		for k, v := range store[id] {
+			merged[k] = v
+		}
+		for k, v := range value {
+			if v == nil {
+				delete(merged, k)
+			} else {
+				merged[k] = v
+			}
+		}
+		store[id] = merged
+		s.reply(w, http.StatusOK, item(id, merged))
+	case r.Method == http.MethodDelete:
+		if kind == "ssls" && s.failDelete[id] {
+			s.reply(w, http.StatusInternalServerError, map[string]any{"error_msg": "etcd unavailable"})
+			return
+		}
+		if _, ok := store[id]; !ok {
+			s.reply(w, http.StatusNotFound, map[string]any{"message": "Key not found"})
+			return
+		}
+		delete(store, id)
+		s.reply(w, http.StatusOK, map[string]any{"deleted": "1", "key": "/apisix/" + kind + "/" + id})
+	default:
+		s.reply(w, http.StatusMethodNotAllowed, map[string]any{"error_msg": "method not allowed"})
+	}
+}
+
+// runAction 以完整的请求流程（校验、分发、输出）执行一个动作，返回解析后的响应
+func runAction(t *testing.T, action string, params map[string]any) Response {
+	t.Helper()
+	input, err := json.Marshal(map[string]any{"action": action, "params": params})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	responseOut = &out
+	defer func() { responseOut = os.Stdout }()
+	handleRequest(input)
+	var resp Response
+	if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response %q: %v", out.String(), err)
+	}
+	return resp
+}
@@ -0,0 +1,205 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/network"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// 集成测试针对真实的 APISIX Admin API 运行：
+//
+//	go test -tags integration -run Integration ./...
+//
+// 设置 APISIX_ADMIN_URL 与 APISIX_ADMIN_KEY 时直接使用该网关（测试只操作本次上传的证书，
+// 结束时删除）；未设置时通过 testcontainers-go 启动 etcd 与 APISIX（镜像可用 APISIX_IMAGE、ETCD_IMAGE 覆盖）。
+
+const (
+	defaultAPISIXImage = "apache/apisix:3.9.1-debian"
+	defaultETCDImage   = "quay.io/coreos/etcd:v3.5.12"
+	integrationKey     = "allinssl-integration-key"
+)
+
+// integrationGateway 返回 Admin API 地址与 admin key
+func integrationGateway(t *testing.T) (string, string) {
+	t.Helper()
+	if url := os.Getenv("APISIX_ADMIN_URL"); url != "" {
+		key := os.Getenv("APISIX_ADMIN_KEY")
+		if key == "" {
+			t.Fatal("APISIX_ADMIN_KEY is required when APISIX_ADMIN_URL is set")
+		}
+		return strings.TrimRight(url, "/"), key
+	}
+	return startAPISIX(t), integrationKey
+}
+
+// startAPISIX 在独立的容器网络中启动 etcd 与 APISIX，测试结束后删除
+func startAPISIX(t *testing.T) string {
+	t.Helper()
+	ctx := context.Background()
+	nw, err := network.New(ctx)
+	testcontainers.CleanupNetwork(t, nw)
+	if err != nil {
+		t.Fatalf("failed to create network: %v", err)
+	}
+
+	etcd, err := testcontainers.Run(ctx, envOr("ETCD_IMAGE", defaultETCDImage),
+		network.WithNetwork([]string{"etcd"}, nw),
+		testcontainers.WithCmd("etcd",
+			"--listen-client-urls", "http://0.0.0.0:2379",
+			"--advertise-client-urls", "http://etcd:2379"),
+		testcontainers.WithWaitStrategy(wait.ForLog("ready to serve client requests")),
+	)
+	testcontainers.CleanupContainer(t, etcd)
+	if err != nil {
+		t.Fatalf("failed to start etcd: %v", err)
+	}
+
+	config := fmt.Sprintf(`deployment:
+  role: traditional
+  role_traditional:
+    config_provider: etcd
+  admin:
+    admin_key:
+      - name: admin
+        key: %s
+        role: admin
+    allow_admin:
+      - 0.0.0.0/0
+  etcd:
+    host:
+      - "http://etcd:2379"
+`, integrationKey)
+	apisix, err := testcontainers.Run(ctx, envOr("APISIX_IMAGE", defaultAPISIXImage),
+		network.WithNetwork([]string{"apisix"}, nw),
+		testcontainers.WithExposedPorts("9180/tcp"),
+		testcontainers.WithFiles(testcontainers.ContainerFile{
+			Reader:            strings.NewReader(config),
+			ContainerFilePath: "/usr/local/apisix/conf/config.yaml",
+			FileMode:          0644,
+		}),
+		testcontainers.WithWaitStrategy(wait.ForHTTP(adminPathPrefix+"/ssls").
+			WithPort("9180/tcp").
+			WithHeaders(map[string]string{"X-API-KEY": integrationKey}).
+			WithStartupTimeout(90*time.Second)),
+	)
+	testcontainers.CleanupContainer(t, apisix)
+	if err != nil {
+		t.Fatalf("failed to start APISIX: %v", err)
+	}
+	url, err := apisix.PortEndpoint(ctx, "9180/tcp", "http")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return url
+}
+
+func envOr(name, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// listedCert 在 list_certs 结果中查找指定 id
+func listedCert(t *testing.T, params map[string]any, id string) map[string]any {
+	t.Helper()
+	resp := runAction(t, "list_certs", params)
+	if resp.Status != "success" {
+		t.Fatalf("list_certs: %s", resp.Message)
+	}
+	certs, _ := resp.Result["certs"].([]any)
+	if count, _ := resp.Result["count"].(float64); int(count) != len(certs) {
+		t.Errorf("list_certs count = %v, certs = %d", resp.Result["count"], len(certs))
+	}
+	for _, c := range certs {
+		if m, _ := c.(map[string]any); m["cert_id"] == id {
+			return m
+		}
+	}
+	return nil
+}
+
+// TestIntegrationLifecycle 依次执行上传、列出、续期替换与删除，断言真实网关的响应
+func TestIntegrationLifecycle(t *testing.T) {
+	url, key := integrationGateway(t)
+	domain := fmt.Sprintf("it-%d.allinssl.test", time.Now().UnixNano())
+	params := func(extra map[string]any) map[string]any {
+		p := map[string]any{"server_address": url, "admin_key": key}
+		for k, v := range extra {
+			p[k] = v
+		}
+		return p
+	}
+
+	certPEM, keyPEM := testCert(t, domain)
+	resp := runAction(t, "upload_bind", params(map[string]any{"cert": certPEM, "key": keyPEM}))
+	if resp.Status != "success" {
+		t.Fatalf("upload_bind: %s", resp.Message)
+	}
+	firstID, _ := resp.Result["cert_id"].(string)
+	if firstID == "" || resp.Result["changed"] != true {
+		t.Fatalf("upload_bind result = %v", resp.Result)
+	}
+	t.Cleanup(func() {
+		runAction(t, "delete_cert", params(map[string]any{"cert_id": firstID}))
+	})
+
+	listed := listedCert(t, params(nil), firstID)
+	if listed == nil {
+		t.Fatalf("list_certs does not include %s", firstID)
+	}
+	if snis, _ := listed["snis"].([]any); len(snis) != 1 || snis[0] != domain {
+		t.Errorf("list_certs snis = %v, want [%s]", listed["snis"], domain)
+	}
+
+	// 相同证书再次上传应复用已有对象
+	resp = runAction(t, "upload_bind", params(map[string]any{"cert": certPEM, "key": keyPEM}))
+	if resp.Status != "success" || resp.Result["cert_id"] != firstID || resp.Result["changed"] != false {
+		t.Fatalf("re-upload: %s %v", resp.Message, resp.Result)
+	}
+
+	// 续期：同域名的新证书替换旧证书并删除旧对象
+	renewedPEM, renewedKey := testCert(t, domain)
+	resp = runAction(t, "upload_bind", params(map[string]any{"cert": renewedPEM, "key": renewedKey}))
+	if resp.Status != "success" {
+		t.Fatalf("renew: %s", resp.Message)
+	}
+	renewedID, _ := resp.Result["cert_id"].(string)
+	t.Cleanup(func() {
+		runAction(t, "delete_cert", params(map[string]any{"cert_id": renewedID}))
+	})
+	if renewedID == firstID {
+		t.Fatalf("renew reused the old object %s", firstID)
+	}
+	if deleted, _ := resp.Result["deleted_ids"].([]any); len(deleted) != 1 || deleted[0] != firstID {
+		t.Errorf("renew deleted_ids = %v, want [%s]", resp.Result["deleted_ids"], firstID)
+	}
+	if listedCert(t, params(nil), firstID) != nil {
+		t.Errorf("old cert %s still listed after renew", firstID)
+	}
+
+	resp = runAction(t, "get_cert_status", params(map[string]any{"cert_id": renewedID}))
+	if resp.Status != "success" || resp.Result["managed"] != true {
+		t.Errorf("get_cert_status: %s %v", resp.Message, resp.Result)
+	}
+
+	resp = runAction(t, "delete_cert", params(map[string]any{"cert_id": renewedID}))
+	if resp.Status != "success" {
+		t.Fatalf("delete_cert: %s", resp.Message)
+	}
+	if deleted, _ := resp.Result["deleted"].([]any); len(deleted) != 1 || deleted[0] != renewedID {
+		t.Errorf("delete_cert deleted = %v, want [%s]", resp.Result["deleted"], renewedID)
+	}
+	if listedCert(t, params(nil), renewedID) != nil {
+		t.Errorf("cert %s still listed after delete", renewedID)
+	}
+}
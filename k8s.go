@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// applyK8sSecret 将 k8s_secret 参数（kubernetes.io/tls 类型 Secret 的 JSON 对象，或 JSON/YAML 文本）
+// 中的 tls.crt/tls.key 解码后填入 cert/key，后续按普通 cert/key 流程处理。
+// data 中的值为 base64，stringData 中的值为明文
+func applyK8sSecret(cfg map[string]any) error {
+	v, ok := cfg["k8s_secret"]
+	if !ok || v == nil {
+		return nil
+	}
+	if c, _ := cfg["cert"].(string); c != "" {
+		return fmt.Errorf("k8s_secret cannot be combined with cert")
+	}
+	if k, _ := cfg["key"].(string); k != "" {
+		return fmt.Errorf("k8s_secret cannot be combined with key")
+	}
+	var secret map[string]any
+	switch s := v.(type) {
+	case map[string]any:
+		secret = s
+	case string:
+		if err := json.Unmarshal([]byte(s), &secret); err != nil {
+			secret = parseSecretYAML(s)
+		}
+	default:
+		return fmt.Errorf("k8s_secret must be an object or a JSON/YAML string")
+	}
+	if t, _ := secret["type"].(string); t != "" && t != "kubernetes.io/tls" {
+		return fmt.Errorf("k8s_secret type is %s, expected kubernetes.io/tls", t)
+	}
+	for _, name := range []string{"tls.crt", "tls.key"} {
+		value, err := secretValue(secret, name)
+		if err != nil {
+			return err
+		}
+		if name == "tls.crt" {
+			cfg["cert"] = value
+		} else {
+			cfg["key"] = value
+		}
+	}
+	return nil
+}
+
+// secretValue 读取 Secret 中的一项：优先 stringData（明文），其次 data（base64 解码）
+func secretValue(secret map[string]any, name string) (string, error) {
+	if stringData, ok := secret["stringData"].(map[string]any); ok {
+		if s, ok := stringData[name].(string); ok && s != "" {
+			return s, nil
+		}
+	}
+	data, _ := secret["data"].(map[string]any)
+	s, ok := data[name].(string)
+	if !ok || s == "" {
+		return "", fmt.Errorf("k8s_secret has no %s in data or stringData", name)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(s), ""))
+	if err != nil {
+		return "", fmt.Errorf("k8s_secret data.%s is not valid base64: %w", name, err)
+	}
+	return string(decoded), nil
+}
+
+// parseSecretYAML 解析 kubectl get secret -o yaml 的输出中用到的部分：顶层 type，
+// 以及 data/stringData 下的单行 "key: value" 项。不依赖 YAML 库，不支持多行或嵌套写法
+func parseSecretYAML(text string) map[string]any {
+	secret := map[string]any{}
+	var section map[string]any
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxInputSize)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || trimmed == "---" {
+			continue
+		}
+		name, value, found := strings.Cut(trimmed, ":")
+		if !found {
+			continue
+		}
+		name = strings.TrimSpace(name)
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+		if line[0] != ' ' && line[0] != '\t' {
+			// 顶层字段
+			section = nil
+			switch {
+			case (name == "data" || name == "stringData") && value == "":
+				section = map[string]any{}
+				secret[name] = section
+			case name == "type":
+				secret["type"] = value
+			}
+			continue
+		}
+		if section != nil && value != "" {
+			section[name] = value
+		}
+	}
+	return secret
+}
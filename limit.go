@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// 同时进行中的写操作（Admin API、manager-api 的 POST/PUT/PATCH/DELETE 与 etcd put）上限，默认 4
+const defaultMaxConcurrency = 4
+
+// writeSlots 为全进程共享的写操作信号量，多网关、多分组并发部署时共同受其约束
+var writeSlots = make(chan struct{}, defaultMaxConcurrency)
+
+// setMaxConcurrency 按 max_concurrency 参数重建信号量，需在发起任何请求前调用
+func setMaxConcurrency(n int) error {
+	if n <= 0 {
+		return fmt.Errorf("max_concurrency must be a positive integer")
+	}
+	writeSlots = make(chan struct{}, n)
+	return nil
+}
+
+// isWrite 判断 method 是否会修改网关配置
+func isWrite(method string) bool {
+	return method != "GET" && method != "HEAD"
+}
+
+// acquireSlot 为 HTTP 写操作占用一个并发名额，读操作不占用。
+// 名额只在请求进行期间占用，429 重试的等待期间会先释放，避免退避中的请求阻塞其他请求
+func acquireSlot(ctx context.Context, method string) (func(), error) {
+	if !isWrite(method) {
+		return func() {}, nil
+	}
+	return acquireWrite(ctx)
+}
+
+// acquireWrite 为一次写操作占用一个并发名额，ctx 取消时放弃等待；
+// 所有后端（Admin API、manager-api、etcd）的写路径都经由它，共同受 max_concurrency 约束
+func acquireWrite(ctx context.Context) (func(), error) {
+	select {
+	case writeSlots <- struct{}{}:
+		return func() { <-writeSlots }, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("waiting for a free request slot: %w", ctx.Err())
+	}
+}
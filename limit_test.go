@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// TestWriteSlotsSharedByBackends 名额占满时 manager-api 与 etcd 的写操作同样等待，不会直接发出请求
+func TestWriteSlotsSharedByBackends(t *testing.T) {
+	saved, savedCtx := writeSlots, runCtx
+	t.Cleanup(func() { writeSlots, runCtx = saved, savedCtx })
+	writeSlots = make(chan struct{}, 1)
+	writeSlots <- struct{}{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	dash := newDashStub(t)
+	d := NewDashboard("admin", "pass", dash.URL)
+	d.ctx = ctx
+	if _, err := d.dashboardAPI("/apisix/admin/ssl/1", map[string]any{"cert": "c"}, "PUT"); err == nil || !strings.Contains(err.Error(), "free request slot") {
+		t.Errorf("dashboard PUT error = %v, want a slot wait error", err)
+	}
+	// 读操作不占用名额
+	d.ctx = context.Background()
+	d.dashboardAPI("/apisix/admin/ssl", nil, "GET")
+	if got := dash.requested(); len(got) != 1 || got[0] != "GET /apisix/admin/ssl" {
+		t.Errorf("dashboard requests = %v, want only the GET", got)
+	}
+
+	var puts atomic.Int32
+	etcd := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		puts.Add(1)
+		w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(etcd.Close)
+	runCtx = ctx
+	e := &EtcdClient{Endpoints: []string{etcd.URL}, Prefix: "/apisix", client: etcd.Client()}
+	if _, err := e.putSSL("1", "cert", "key", "", []string{"a.test"}, nil); err == nil || !strings.Contains(err.Error(), "free request slot") {
+		t.Errorf("etcd put error = %v, want a slot wait error", err)
+	}
+	if puts.Load() != 0 {
+		t.Errorf("etcd received %d requests while no slot was free", puts.Load())
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// 单页查询默认条数；APISIX v3 要求 page_size 在 10~500 之间
+const (
+	defaultPageSize = 100
+	minPageSize     = 10
+	maxPageSize     = 500
+)
+
+// pageLister 由支持服务端分页的后端实现
+type pageLister interface {
+	listCertPage(page, pageSize int) ([]map[string]any, int, error)
+}
+
+// certSummary 提取 SSL 对象中用于展示的字段
+func certSummary(value map[string]any) map[string]any {
+	id, _ := value["id"].(string)
+	snis, _ := snisFromValue(value)
+	item := map[string]any{
+		"cert_id": id,
+		"desc":    value["desc"],
+		"snis":    snis,
+		"labels":  value["labels"],
+	}
+	if notAfter, _, ok := certNotAfter(value); ok {
+		item["not_after"] = notAfter.UTC().Format(time.RFC3339)
+	}
+	return item
+}
+
+// ListCerts 列出证书，默认只返回托管证书（all=true 时返回全部）。
+// 传入 page 时只返回单页，托管过滤在该页内进行，total 为网关上 SSL 对象总数
+func ListCerts(cfg map[string]any) (*Response, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config cannot be nil")
+	}
+	a, err := backendFromParams(cfg)
+	if err != nil {
+		return nil, err
+	}
+	all, _ := cfg["all"].(bool)
+	page, err := intParam(cfg, "page", 0)
+	if err != nil {
+		return nil, err
+	}
+	pageSize, err := intParam(cfg, "page_size", defaultPageSize)
+	if err != nil {
+		return nil, err
+	}
+	if page < 0 {
+		return nil, fmt.Errorf("page must be a positive integer")
+	}
+	if page > 0 && (pageSize < minPageSize || pageSize > maxPageSize) {
+		return nil, fmt.Errorf("page_size must be between %d and %d", minPageSize, maxPageSize)
+	}
+
+	var certServer []map[string]any
+	total := -1
+	if page == 0 {
+		certServer, err = a.listCertFromApisix()
+	} else if p, ok := a.(pageLister); ok {
+		certServer, total, err = p.listCertPage(page, pageSize)
+	} else {
+		// 后端不支持服务端分页时，拉取全部后在本地切片
+		certServer, err = a.listCertFromApisix()
+		if err == nil {
+			total = len(certServer)
+			start := min((page-1)*pageSize, total)
+			end := min(start+pageSize, total)
+			certServer = certServer[start:end]
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list certs from Apisix: %w", err)
+	}
+
+	certs := make([]map[string]any, 0, len(certServer))
+	for _, cert := range certServer {
+		value, ok := cert["value"].(map[string]any)
+		if !ok || (!all && !isManagedCert(value)) {
+			continue
+		}
+		certs = append(certs, certSummary(value))
+	}
+	result := map[string]any{
+		"count": len(certs),
+		"certs": certs,
+	}
+	if page > 0 {
+		result["page"] = page
+		result["page_size"] = pageSize
+		if total >= 0 {
+			result["total"] = total
+		}
+	}
+	attachDiagnostics(a, result)
+	return &Response{
+		Status:  "success",
+		Message: "Certificates listed successfully",
+		Result:  result,
+	}, nil
+}
@@ -2,14 +2,15 @@ package main
 
 import (
 	"crypto/sha256"
-	"crypto/x509"
 	_ "embed"
 	"encoding/hex"
 	"encoding/json"
-	"encoding/pem"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"os"
+	"time"
 )
 
 type Request struct {
@@ -19,6 +20,7 @@ type Request struct {
 
 type Response struct {
 	Status  string                 `json:"status"`
+	Code    string                 `json:"code,omitempty"`
 	Message string                 `json:"message"`
 	Result  map[string]interface{} `json:"result"`
 }
@@ -26,52 +28,256 @@ type Response struct {
 //go:embed metadata.json
 var metadataJSON []byte
 
+// pluginMeta 为进程启动时确定的元数据：内嵌元数据，或 ALLINSSL_METADATA_FILE 指定的文件
 var pluginMeta map[string]interface{}
 
+// 指定外部元数据文件的环境变量，无需重新构建即可修正对外声明的 schema
+const metadataFileEnv = "ALLINSSL_METADATA_FILE"
+
 func init() {
 	if err := json.Unmarshal(metadataJSON, &pluginMeta); err != nil {
 		panic(fmt.Sprintf("解析元数据失败: %v", err))
 	}
+	if path := os.Getenv(metadataFileEnv); path != "" {
+		if meta, err := loadMetadata(path); err == nil {
+			pluginMeta = meta
+		} else {
+			debugf("ignoring %s, using embedded metadata: %v", metadataFileEnv, err)
+		}
+	}
 }
 
-func GetSHA256(certStr string) (string, error) {
-	certPEM := []byte(certStr)
-	block, _ := pem.Decode(certPEM)
-	if block == nil {
-		return "", fmt.Errorf("无法解析证书 PEM")
+// loadMetadata 从文件读取元数据，要求是包含 actions 数组的 JSON 对象
+func loadMetadata(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
 	}
-	cert, err := x509.ParseCertificate(block.Bytes)
+	var meta map[string]interface{}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("metadata file is not valid JSON: %w", err)
+	}
+	if _, ok := meta["actions"].([]interface{}); !ok {
+		return nil, fmt.Errorf("metadata file has no actions array")
+	}
+	return meta, nil
+}
+
+func GetSHA256(certStr string) (string, error) {
+	cert, err := parseLeaf(certStr)
 	if err != nil {
-		return "", fmt.Errorf("解析证书失败: %v", err)
+		return "", err
 	}
 
 	sha256Hash := sha256.Sum256(cert.Raw)
 	return hex.EncodeToString(sha256Hash[:]), nil
 }
 
+// debugf 在设置 ALLINSSL_DEBUG 环境变量时向 stderr 输出调试日志，不影响 stdout 上的 JSON 响应
+func debugf(format string, args ...any) {
+	if os.Getenv("ALLINSSL_DEBUG") == "" {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "[debug] "+format+"\n", args...)
+}
+
+// responseOut 为响应的输出位置，默认 stdout，socket 模式下为当前连接
+var responseOut io.Writer = os.Stdout
+
 func outputJSON(resp *Response) {
-	_ = json.NewEncoder(os.Stdout).Encode(resp)
+	if configEcho != nil {
+		// 复制 Result 与 Response 后再追加：get_metadata 的 Result 是全局元数据，不能被写入
+		result := make(map[string]interface{}, len(resp.Result)+1)
+		for k, v := range resp.Result {
+			result[k] = v
+		}
+		result["effective_config"] = configEcho
+		copied := *resp
+		copied.Result = result
+		resp = &copied
+	}
+	resp = applyStrict(resp)
+	if quietOutput {
+		_ = writeResponse(responseOut, quietResponse(resp))
+	} else {
+		_ = writeResponse(responseOut, resp)
+	}
+	// 指标写入失败不影响已输出的响应，只记录调试日志
+	if metricsFile != "" {
+		if err := writeMetrics(metricsFile, requestAction, resp); err != nil {
+			debugf("failed to write metrics file %s: %v", metricsFile, err)
+		}
+	}
 }
 
 func outputError(msg string, err error) {
-	outputJSON(&Response{
+	resp := &Response{
 		Status:  "error",
+		Code:    errorCode(err),
 		Message: fmt.Sprintf("%s: %v", msg, err),
-	})
+	}
+	result := map[string]interface{}{}
+	// 参数校验错误附带逐字段的 errors 数组
+	var ve validationErrors
+	if errors.As(err, &ve) {
+		result["errors"] = ve
+	}
+	// 重试后仍失败时附带每次尝试的错误，便于排查偶发故障
+	var re *retryError
+	if errors.As(err, &re) {
+		result["retry_info"] = re.Info
+	}
+	// Admin API 返回非 2xx 时分别给出 HTTP 状态码与 APISIX 的错误码/错误信息
+	var ae *apiError
+	if errors.As(err, &ae) {
+		for k, v := range ae.details() {
+			result[k] = v
+		}
+	}
+	if len(result) > 0 {
+		resp.Result = result
+	}
+	outputJSON(resp)
+}
+
+// 未通过 -stdin-timeout 指定时读取的环境变量，取值为 Go duration（如 30s）
+const stdinTimeoutEnv = "ALLINSSL_STDIN_TIMEOUT"
+
+// readInput 读取请求内容：指定了文件时从文件读取，否则读取 stdin。
+// timeout 大于 0 时，超时仍未读到 EOF 则返回错误，避免调用方不关闭 stdin 导致进程挂起
+func readInput(requestFile string, timeout time.Duration) ([]byte, error) {
+	if requestFile != "" {
+		return os.ReadFile(requestFile)
+	}
+	if timeout <= 0 {
+		return io.ReadAll(os.Stdin)
+	}
+	type readResult struct {
+		data []byte
+		err  error
+	}
+	done := make(chan readResult, 1)
+	go func() {
+		data, err := io.ReadAll(os.Stdin)
+		done <- readResult{data, err}
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case r := <-done:
+		return r.data, r.err
+	case <-timer.C:
+		return nil, fmt.Errorf("no complete request received on stdin within %s", timeout)
+	}
 }
 
 func main() {
-	var req Request
-	input, err := io.ReadAll(os.Stdin)
+	requestFile := flag.String("f", "", "从文件读取 JSON 请求，默认读取 stdin")
+	stdinTimeout := flag.Duration("stdin-timeout", 0, "读取 stdin 的超时时间（如 30s），默认不限制，也可通过 "+stdinTimeoutEnv+" 设置")
+	socketPath := flag.String("socket", "", "监听指定的 Unix domain socket，通过连接而不是 stdin/stdout 交换请求与响应")
+	socketCount := flag.Int("socket-count", 1, "socket 模式下处理的请求数，处理完后退出")
+	showVersion := flag.Bool("version", false, "输出版本与构建信息后退出")
+	flag.Usage = func() { printUsage(os.Stdout) }
+	flag.Parse()
+	// --version/--help 在读取 stdin 之前处理，直接运行二进制时不会阻塞等待输入
+	if *showVersion {
+		printVersion(os.Stdout)
+		return
+	}
+	if *socketPath != "" {
+		// socket 模式下收到信号时关闭监听并退出，删除 socket 文件
+		stop := installSignalHandler()
+		defer stop()
+		if err := serveSocket(*socketPath, *socketCount); err != nil {
+			outputError("socket 模式失败", err)
+		}
+		return
+	}
+	// 也支持将文件路径作为第一个位置参数传入
+	if *requestFile == "" && flag.NArg() > 0 {
+		*requestFile = flag.Arg(0)
+	}
+
+	if *stdinTimeout == 0 {
+		if v := os.Getenv(stdinTimeoutEnv); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				outputError("读取输入失败", fmt.Errorf("invalid %s: %w", stdinTimeoutEnv, err))
+				return
+			}
+			*stdinTimeout = d
+		}
+	}
+	input, err := readInput(*requestFile, *stdinTimeout)
 	if err != nil {
 		outputError("读取输入失败", err)
 		return
 	}
+	handleRequest(input)
+}
 
+// handleRequest 解析并执行一次请求，响应写入 responseOut；
+// 每次请求前重置由请求参数决定的全局设置，socket 模式下多次请求互不影响
+func handleRequest(input []byte) {
+	outputFormat = "json"
+	managedByField = "desc"
+	configEcho = nil
+	// 证书链较长、域名较多时调用方可以 gzip 压缩请求内容
+	input, err := decompressInput(input)
+	if err != nil {
+		outputError("解析请求失败", err)
+		return
+	}
+	var req Request
 	if err := json.Unmarshal(input, &req); err != nil {
 		outputError("解析请求失败", err)
 		return
 	}
+	if format, ok := req.Params["output_format"].(string); ok && format != "" {
+		switch format {
+		case "json", "pretty", "yaml", "text":
+			outputFormat = format
+		default:
+			outputError("解析请求失败", fmt.Errorf("unsupported output_format: %s", format))
+			return
+		}
+	}
+
+	requestAction = req.Action
+	metricsFile, _ = req.Params["metrics_file"].(string)
+	quietOutput, _ = req.Params["quiet"].(bool)
+	strictMode, _ = req.Params["strict"].(bool)
+	maxConcurrency, err := intParam(req.Params, "max_concurrency", defaultMaxConcurrency)
+	if err == nil {
+		err = setMaxConcurrency(maxConcurrency)
+	}
+	if err != nil {
+		outputError("解析请求失败", err)
+		return
+	}
+	if field, ok := req.Params["managed_by_field"].(string); ok && field != "" {
+		if field != "desc" && field != "labels" {
+			outputError("解析请求失败", fmt.Errorf("unsupported managed_by_field: %s", field))
+			return
+		}
+		managedByField = field
+	}
+
+	if req.Params == nil {
+		req.Params = map[string]interface{}{}
+	}
+	envApplied := applyEnvDefaults(req.Params)
+	if err := applyK8sSecret(req.Params); err != nil {
+		outputError("解析请求失败", err)
+		return
+	}
+	if err := validateParams(req.Action, req.Params); err != nil {
+		outputError("参数校验失败", err)
+		return
+	}
+	if echo, _ := req.Params["config_echo"].(bool); echo {
+		configEcho = effectiveConfig(req.Params, envApplied)
+	}
 
 	switch req.Action {
 	case "get_metadata":
@@ -84,7 +290,7 @@ func main() {
 		outputJSON(&Response{
 			Status:  "success",
 			Message: "支持的动作",
-			Result:  map[string]interface{}{"actions": pluginMeta["actions"]},
+			Result:  map[string]interface{}{"actions": actionsWithSchemas()},
 		})
 	case "upload_bind":
 		rep, err := Upload_bind(req.Params)
@@ -93,6 +299,153 @@ func main() {
 			return
 		}
 		outputJSON(rep)
+	case "backup":
+		rep, err := Backup(req.Params)
+		if err != nil {
+			outputError("备份证书失败", err)
+			return
+		}
+		outputJSON(rep)
+	case "restore":
+		rep, err := Restore(req.Params)
+		if err != nil {
+			outputError("恢复证书失败", err)
+			return
+		}
+		outputJSON(rep)
+	case "diff":
+		rep, err := Diff(req.Params)
+		if err != nil {
+			outputError("对比证书失败", err)
+			return
+		}
+		outputJSON(rep)
+	case "list_expiring":
+		rep, err := ListExpiring(req.Params)
+		if err != nil {
+			outputError("查询即将过期证书失败", err)
+			return
+		}
+		outputJSON(rep)
+	case "count":
+		rep, err := Count(req.Params)
+		if err != nil {
+			outputError("统计证书失败", err)
+			return
+		}
+		outputJSON(rep)
+	case "list_certs":
+		rep, err := ListCerts(req.Params)
+		if err != nil {
+			outputError("查询证书失败", err)
+			return
+		}
+		outputJSON(rep)
+	case "whoami":
+		rep, err := Whoami(req.Params)
+		if err != nil {
+			outputError("查询当前配置失败", err)
+			return
+		}
+		outputJSON(rep)
+	case "upload_only":
+		rep, err := UploadOnly(req.Params)
+		if err != nil {
+			outputError("上传证书失败", err)
+			return
+		}
+		outputJSON(rep)
+	case "bind_only":
+		rep, err := BindOnly(req.Params)
+		if err != nil {
+			outputError("绑定域名失败", err)
+			return
+		}
+		outputJSON(rep)
+	case "prune":
+		rep, err := Prune(req.Params)
+		if err != nil {
+			outputError("清理证书失败", err)
+			return
+		}
+		outputJSON(rep)
+	case "get_cert_status":
+		rep, err := GetCertStatus(req.Params)
+		if err != nil {
+			outputError("查询证书状态失败", err)
+			return
+		}
+		outputJSON(rep)
+	case "consumer_mtls":
+		rep, err := ConsumerMTLS(req.Params)
+		if err != nil {
+			outputError("更新 consumer 客户端证书失败", err)
+			return
+		}
+		outputJSON(rep)
+	case "export_pem":
+		rep, err := ExportPEM(req.Params)
+		if err != nil {
+			outputError("导出证书失败", err)
+			return
+		}
+		outputJSON(rep)
+	case "replace_sni":
+		rep, err := ReplaceSNI(req.Params)
+		if err != nil {
+			outputError("替换证书域名失败", err)
+			return
+		}
+		outputJSON(rep)
+	case "test_admin_key":
+		rep, err := TestAdminKey(req.Params)
+		if err != nil {
+			outputError("验证 admin_key 失败", err)
+			return
+		}
+		outputJSON(rep)
+	case "probe":
+		rep, err := Probe(req.Params)
+		if err != nil {
+			outputError("TLS 探测失败", err)
+			return
+		}
+		outputJSON(rep)
+	case "search":
+		rep, err := Search(req.Params)
+		if err != nil {
+			outputError("搜索证书失败", err)
+			return
+		}
+		outputJSON(rep)
+	case "reconcile_report":
+		rep, err := ReconcileReport(req.Params)
+		if err != nil {
+			outputError("生成覆盖报告失败", err)
+			return
+		}
+		outputJSON(rep)
+	case "unbind_sni":
+		rep, err := UnbindSNI(req.Params)
+		if err != nil {
+			outputError("解绑域名失败", err)
+			return
+		}
+		outputJSON(rep)
+	case "delete_cert":
+		rep, err := DeleteCert(req.Params)
+		if err != nil {
+			outputError("删除证书失败", err)
+			return
+		}
+		outputJSON(rep)
+	case "upload_bind_and_route":
+		rep, err := UploadBindAndRoute(req.Params)
+		if err != nil {
+			outputError("上传绑定并更新路由失败", err)
+			return
+		}
+		outputJSON(rep)
 	default:
 		outputJSON(&Response{
 			Status:  "error",
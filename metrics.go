@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// metricsFile 非空时，每次运行结束后将结果以 Prometheus textfile 格式写入该文件，
+// 供 node_exporter 的 textfile collector 采集
+var metricsFile string
+
+// requestAction 为本次运行的 action，作为指标的 action 标签，并用于补全 Result 字段
+var requestAction string
+
+// countDeleted 统计结果中删除的证书数量。deleted 为 id 列表或布尔值（unbind_sni）；
+// 多目标部署的 servers、groups、targets 等按目标汇总的 map 逐层递归累加
+func countDeleted(result map[string]any) int {
+	n := 0
+	switch deleted := result["deleted"].(type) {
+	case []string:
+		n += len(deleted)
+	case []any:
+		n += len(deleted)
+	case bool:
+		if deleted {
+			n++
+		}
+	}
+	for _, v := range result {
+		perTarget, ok := v.(map[string]any)
+		if !ok || !isMapOfMaps(perTarget) {
+			continue
+		}
+		for _, entry := range perTarget {
+			n += countDeleted(entry.(map[string]any))
+		}
+	}
+	return n
+}
+
+// isMapOfMaps 判断 m 非空且所有值都是 map，即按目标汇总的结果
+func isMapOfMaps(m map[string]any) bool {
+	if len(m) == 0 {
+		return false
+	}
+	for _, v := range m {
+		if _, ok := v.(map[string]any); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// renderMetrics 将一次运行的结果渲染为 Prometheus 文本格式
+func renderMetrics(action string, resp *Response, now time.Time) string {
+	success := 0
+	if resp.Status == "success" {
+		success = 1
+	}
+	label := fmt.Sprintf(`{action=%q}`, action)
+	var b strings.Builder
+	b.WriteString("# HELP allinssl_upload_success Whether the last run succeeded (1) or not (0).\n")
+	b.WriteString("# TYPE allinssl_upload_success gauge\n")
+	fmt.Fprintf(&b, "allinssl_upload_success%s %d\n", label, success)
+	b.WriteString("# HELP allinssl_certs_deleted Number of certificates deleted by the last run.\n")
+	b.WriteString("# TYPE allinssl_certs_deleted gauge\n")
+	fmt.Fprintf(&b, "allinssl_certs_deleted%s %d\n", label, countDeleted(resp.Result))
+	b.WriteString("# HELP allinssl_last_run_timestamp Unix time of the last run.\n")
+	b.WriteString("# TYPE allinssl_last_run_timestamp gauge\n")
+	fmt.Fprintf(&b, "allinssl_last_run_timestamp%s %d\n", label, now.Unix())
+	return b.String()
+}
+
+// writeMetrics 先写临时文件再重命名，避免 node_exporter 读到写了一半的文件
+func writeMetrics(path, action string, resp *Response) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".allinssl-metrics-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(renderMetrics(action, resp, time.Now())); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
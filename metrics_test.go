@@ -0,0 +1,70 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCountDeleted(t *testing.T) {
+	tests := []struct {
+		name   string
+		result map[string]any
+		want   int
+	}{
+		{"nil", nil, 0},
+		{"single", map[string]any{"deleted": []string{"1", "2"}}, 2},
+		{"unbind bool", map[string]any{"deleted": true}, 1},
+		{"unbind kept", map[string]any{"deleted": false}, 0},
+		{
+			"servers",
+			map[string]any{"servers": map[string]any{
+				"a": map[string]any{"deleted": []string{"1"}},
+				"b": map[string]any{"deleted": []string{"2", "3"}},
+			}},
+			3,
+		},
+		{
+			"groups",
+			map[string]any{"groups": map[string]any{
+				"g1": map[string]any{"deleted": []string{"1"}},
+				"g2": map[string]any{"success": false},
+			}},
+			1,
+		},
+		{
+			"targets",
+			map[string]any{"targets": map[string]any{
+				"a#g1": map[string]any{"deleted": []string{"1"}},
+				"a#g2": map[string]any{"deleted": []string{"2"}},
+			}},
+			2,
+		},
+		{
+			"ignores plain maps",
+			map[string]any{"labels": map[string]any{"deleted": "x"}, "rollback": map[string]any{"restored": []string{"1"}}},
+			0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := countDeleted(tt.result); got != tt.want {
+				t.Errorf("countDeleted() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderMetrics(t *testing.T) {
+	resp := &Response{Status: "success", Result: map[string]any{"deleted": []string{"1"}}}
+	out := renderMetrics("upload_bind", resp, time.Unix(100, 0))
+	for _, want := range []string{
+		`allinssl_upload_success{action="upload_bind"} 1`,
+		`allinssl_certs_deleted{action="upload_bind"} 1`,
+		`allinssl_last_run_timestamp{action="upload_bind"} 100`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("renderMetrics() missing %q in:\n%s", want, out)
+		}
+	}
+}
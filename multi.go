@@ -0,0 +1,264 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// 多网关并发上传时默认的并发数
+const defaultConcurrency = 4
+
+// serverAddresses 读取 server_address，支持单个地址或地址数组
+func serverAddresses(cfg map[string]any) ([]string, error) {
+	switch v := cfg["server_address"].(type) {
+	case string:
+		return []string{v}, nil
+	case []any:
+		servers := make([]string, 0, len(v))
+		for i, item := range v {
+			s, ok := item.(string)
+			if !ok || s == "" {
+				return nil, fmt.Errorf("server_address element at index %d is not a string", i)
+			}
+			servers = append(servers, s)
+		}
+		if len(servers) == 0 {
+			return nil, fmt.Errorf("server_address is required and must be a string")
+		}
+		return servers, nil
+	default:
+		return []string{}, nil
+	}
+}
+
+// singleServerAddress 读取单个网关使用的 server_address，只有一个元素的数组按字符串处理
+func singleServerAddress(cfg map[string]any) (string, error) {
+	servers, err := serverAddresses(cfg)
+	if err != nil {
+		return "", err
+	}
+	if len(servers) > 1 {
+		return "", fmt.Errorf("multiple server_address values are only supported by upload_bind")
+	}
+	if len(servers) == 0 || servers[0] == "" {
+		return "", fmt.Errorf("server_address is required and must be a string")
+	}
+	return servers[0], nil
+}
+
+// gatewayGroups 读取 gateway_group，支持单个分组或分组数组
+func gatewayGroups(cfg map[string]any) ([]string, error) {
+	switch v := cfg["gateway_group"].(type) {
+	case string:
+		if v == "" {
+			return []string{}, nil
+		}
+		return []string{v}, nil
+	case []any:
+		groups := make([]string, 0, len(v))
+		for i, item := range v {
+			s, ok := item.(string)
+			if !ok || s == "" {
+				return nil, fmt.Errorf("gateway_group element at index %d is not a string", i)
+			}
+			groups = append(groups, s)
+		}
+		return groups, nil
+	default:
+		return []string{}, nil
+	}
+}
+
+// bindTarget 为一次多目标部署中的单个目标：网关地址与分组的组合
+type bindTarget struct {
+	Name   string
+	Params map[string]any
+}
+
+// bindTargets 展开 server_address 与 gateway_group 的所有组合；只有一个目标时返回 nil。
+// kind 为结果中汇总各目标的字段名：servers、groups 或两者都有多个时的 targets
+func bindTargets(cfg map[string]any) ([]bindTarget, string, error) {
+	servers, err := serverAddresses(cfg)
+	if err != nil {
+		return nil, "", err
+	}
+	groups, err := gatewayGroups(cfg)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(servers) <= 1 && len(groups) <= 1 {
+		return nil, "", nil
+	}
+	kind := "targets"
+	switch {
+	case len(groups) <= 1:
+		kind = "servers"
+	case len(servers) <= 1:
+		kind = "groups"
+	}
+	targets := make([]bindTarget, 0)
+	for _, server := range servers {
+		params := withParam(cfg, "server_address", server)
+		if len(groups) == 0 {
+			targets = append(targets, bindTarget{Name: server, Params: params})
+			continue
+		}
+		for _, group := range groups {
+			name := group
+			switch kind {
+			case "servers":
+				name = server
+			case "targets":
+				name = server + "#" + group
+			}
+			targets = append(targets, bindTarget{Name: name, Params: withParam(params, "gateway_group", group)})
+		}
+	}
+	// 各目标并发删除冲突证书，备份文件按目标序号区分，避免互相覆盖
+	if backupFile, _ := cfg["backup_file"].(string); backupFile != "" {
+		for i := range targets {
+			targets[i].Params = withParam(targets[i].Params, "backup_file", fmt.Sprintf("%s.%d", backupFile, i+1))
+		}
+	}
+	return targets, kind, nil
+}
+
+// withParam 复制请求参数并覆盖其中一项，用于按网关拆分请求
+func withParam(cfg map[string]any, name string, value any) map[string]any {
+	out := make(map[string]any, len(cfg))
+	for k, v := range cfg {
+		out[k] = v
+	}
+	out[name] = value
+	return out
+}
+
+// copyResult 复制初始结果，避免并发写入同一个 map
+func copyResult(result map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(result))
+	for k, v := range result {
+		if list, ok := v.([]string); ok {
+			v = append([]string(nil), list...)
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// uploadMulti 以有限并发将同一证书绑定到多个目标（网关或网关分组），按目标汇总结果；
+// 部分失败时 status 为 partial，failed_<kind> 列出需要重试的目标
+func uploadMulti(cfg map[string]any, p *bindPlan, targets []bindTarget, kind string, result map[string]interface{}) (*Response, error) {
+	concurrency, err := intParam(cfg, "concurrency", defaultConcurrency)
+	if err != nil {
+		return nil, err
+	}
+	if concurrency <= 0 {
+		return nil, fmt.Errorf("concurrency must be a positive integer")
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	perTarget := make(map[string]any, len(targets))
+	failed := make([]string, 0)
+	for _, target := range targets {
+		sem <- struct{}{}
+		// 收到中断信号后不再向新的目标发起部署，未开始的目标记为失败以便重试
+		if interrupted() {
+			<-sem
+			mu.Lock()
+			perTarget[target.Name] = map[string]any{"success": false, "error": "not attempted: interrupted"}
+			failed = append(failed, target.Name)
+			mu.Unlock()
+			continue
+		}
+		wg.Add(1)
+		go func(target bindTarget) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			entry := map[string]any{"success": false}
+			a, err := backendFromParams(target.Params)
+			var rep *Response
+			if err == nil {
+				rep, err = bindOnBackend(a, p, target.Params, copyResult(result))
+			}
+			// 回滚等情况以 status=error 的响应返回，同样计为失败以便重试
+			ok := err == nil && rep.Status != "error"
+			if err != nil {
+				entry["error"] = err.Error()
+			} else {
+				for k, v := range rep.Result {
+					entry[k] = v
+				}
+				if ok {
+					entry["success"] = true
+				} else {
+					entry["error"] = rep.Message
+				}
+			}
+			mu.Lock()
+			perTarget[target.Name] = entry
+			if !ok {
+				failed = append(failed, target.Name)
+			}
+			mu.Unlock()
+		}(target)
+	}
+	wg.Wait()
+	sort.Strings(failed)
+	// 任一目标有变更即视为变更
+	changed := false
+	for _, entry := range perTarget {
+		if c, _ := entry.(map[string]any)["changed"].(bool); c {
+			changed = true
+		}
+	}
+	result["changed"] = changed
+	// 顶层 cert_id 与 action 取各成功目标一致的值，不一致时分别为空与 mixed；
+	// 各目标删除的证书 id 属于不同网关，只在逐目标结果中列出
+	certID, action := "", ""
+	for _, entry := range perTarget {
+		e := entry.(map[string]any)
+		if ok, _ := e["success"].(bool); !ok {
+			continue
+		}
+		id, _ := e["cert_id"].(string)
+		act, _ := e["action"].(string)
+		if action == "" {
+			certID, action = id, act
+			continue
+		}
+		if id != certID {
+			certID = ""
+		}
+		if act != action {
+			action = "mixed"
+		}
+	}
+	result["cert_id"] = certID
+	result["action"] = action
+	result["snis"] = p.Domain
+	result["deleted_ids"] = []string{}
+
+	result[kind] = perTarget
+	result["failed_"+kind] = failed
+	if interrupted() {
+		result["interrupted"] = true
+	}
+	status, message := "success", fmt.Sprintf("Certificate uploaded and bound on all %s", kind)
+	switch {
+	case len(failed) == len(targets):
+		status, message = "error", fmt.Sprintf("Certificate failed on all %s", kind)
+	case len(failed) > 0:
+		status = "partial"
+		message = fmt.Sprintf("Certificate failed on %d of %d %s: %s", len(failed), len(targets), kind, strings.Join(failed, ", "))
+	}
+	result["message"] = message
+	return &Response{
+		Status:  status,
+		Message: message,
+		Result:  result,
+	}, nil
+}
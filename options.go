@@ -0,0 +1,144 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// AuthOption 用于在构造 Auth 时调整 HTTP 行为，便于作为库嵌入时按需配置
+type AuthOption func(*Auth)
+
+// WithTimeout 设置单次 HTTP 请求的超时时间
+func WithTimeout(d time.Duration) AuthOption {
+	return func(a *Auth) {
+		a.Timeout = d
+	}
+}
+
+// WithRetries 设置遇到 429 限流时的最大重试次数
+func WithRetries(n int) AuthOption {
+	return func(a *Auth) {
+		a.MaxRetries = n
+	}
+}
+
+// WithTLSConfig 设置访问 Admin API 时使用的 TLS 配置（如自签 CA、客户端证书）
+func WithTLSConfig(cfg *tls.Config) AuthOption {
+	return func(a *Auth) {
+		a.TLSConfig = cfg
+	}
+}
+
+// WithHTTPClient 使用调用方提供的 http.Client；其未设置 Transport 时复用 Auth 的共享 transport
+// （TLSConfig 与连接池设置仍然生效），未设置 Timeout 时使用 Auth 的 Timeout
+func WithHTTPClient(c *http.Client) AuthOption {
+	return func(a *Auth) {
+		a.httpClient = c
+	}
+}
+
+// WithAdminPrefix 设置拼接在 ServerAddress 之后的 Admin API 路径前缀（如 /apisix/admin）
+func WithAdminPrefix(prefix string) AuthOption {
+	return func(a *Auth) {
+		a.AdminPrefix = "/" + strings.Trim(prefix, "/")
+		if a.AdminPrefix == "/" {
+			a.AdminPrefix = ""
+		}
+	}
+}
+
+// 连接池默认值：Admin API 流量集中在单个主机且呈突发状，每个主机保留较多空闲连接
+const (
+	defaultMaxIdleConns    = 32
+	defaultIdleConnTimeout = 90 * time.Second
+)
+
+// WithConnPool 设置连接池的最大空闲连接数与空闲连接超时
+func WithConnPool(maxIdleConns int, idleConnTimeout time.Duration) AuthOption {
+	return func(a *Auth) {
+		a.MaxIdleConns = maxIdleConns
+		a.IdleConnTimeout = idleConnTimeout
+	}
+}
+
+// newTransport 按当前配置构造 Auth 共享的 http.Transport，同一 Auth 的所有请求复用其连接池
+func (a *Auth) newTransport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.MaxIdleConns = a.MaxIdleConns
+	t.MaxIdleConnsPerHost = a.MaxIdleConns
+	t.IdleConnTimeout = a.IdleConnTimeout
+	if a.TLSConfig != nil {
+		t.TLSClientConfig = a.TLSConfig
+	}
+	return t
+}
+
+// buildClient 构造发送请求使用的 http.Client：调用方提供的 client 复制后补全 Transport 与 Timeout，
+// 不修改调用方的对象
+func (a *Auth) buildClient() *http.Client {
+	c := &http.Client{}
+	if a.httpClient != nil {
+		*c = *a.httpClient
+	}
+	if c.Transport == nil {
+		c.Transport = a.transport
+	}
+	if c.Timeout == 0 {
+		c.Timeout = a.Timeout
+	}
+	return c
+}
+
+// client 返回发送请求使用的 http.Client；未经 NewAuth 构造的 Auth 按当前配置临时构造
+func (a Auth) client() *http.Client {
+	if a.httpClient != nil {
+		return a.httpClient
+	}
+	if a.transport == nil {
+		a.transport = a.newTransport()
+	}
+	return a.buildClient()
+}
+
+// httpOptions 从请求参数中读取超时、重试、连接池与 TLS 设置，Admin API 与 Dashboard 后端共用。
+// 以 AuthOption 形式返回，由 NewAuth 在应用全部选项后一次性构造 transport
+func httpOptions(cfg map[string]any) ([]AuthOption, error) {
+	maxRetries, err := intParam(cfg, "max_retries", defaultMaxRetries)
+	if err != nil {
+		return nil, err
+	}
+	if maxRetries < 0 {
+		return nil, fmt.Errorf("max_retries must not be negative")
+	}
+	timeout, err := intParam(cfg, "timeout", int(defaultTimeout/time.Second))
+	if err != nil {
+		return nil, err
+	}
+	if timeout <= 0 {
+		return nil, fmt.Errorf("timeout must be a positive number of seconds")
+	}
+	maxIdleConns, err := intParam(cfg, "max_idle_conns", defaultMaxIdleConns)
+	if err != nil {
+		return nil, err
+	}
+	idleConnTimeout, err := intParam(cfg, "idle_conn_timeout_ms", int(defaultIdleConnTimeout/time.Millisecond))
+	if err != nil {
+		return nil, err
+	}
+	if maxIdleConns < 0 || idleConnTimeout < 0 {
+		return nil, fmt.Errorf("max_idle_conns and idle_conn_timeout_ms must not be negative")
+	}
+	opts := []AuthOption{
+		WithRetries(maxRetries),
+		WithTimeout(time.Duration(timeout) * time.Second),
+		WithConnPool(maxIdleConns, time.Duration(idleConnTimeout)*time.Millisecond),
+	}
+	// 自签证书的测试环境可跳过校验，生产环境不建议开启
+	if skip, _ := cfg["insecure_skip_verify"].(bool); skip {
+		opts = append(opts, WithTLSConfig(&tls.Config{InsecureSkipVerify: true}))
+	}
+	return opts, nil
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 输出格式：json（紧凑，默认）、pretty、yaml、text
+var outputFormat = "json"
+
+// quietOutput 为 true 时只返回 status、message 与 cert_id，省略告警与其他信息字段
+var quietOutput bool
+
+// quietResponse 返回只保留 cert_id 的精简响应，不修改原响应
+func quietResponse(resp *Response) *Response {
+	out := &Response{Status: resp.Status, Code: resp.Code, Message: resp.Message}
+	if certID, ok := resp.Result["cert_id"]; ok {
+		out.Result = map[string]interface{}{"cert_id": certID}
+	}
+	return out
+}
+
+// writeResponse 按 outputFormat 渲染 Response
+func writeResponse(w io.Writer, resp *Response) error {
+	switch outputFormat {
+	case "pretty":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(resp)
+	case "yaml":
+		v, err := toGeneric(resp)
+		if err != nil {
+			return err
+		}
+		var b strings.Builder
+		writeYAMLValue(&b, v, 0)
+		_, err = io.WriteString(w, b.String())
+		return err
+	case "text":
+		_, err := io.WriteString(w, renderText(resp))
+		return err
+	default:
+		return json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// toGeneric 经 JSON 往返将任意结构转换为 map/slice/标量，便于统一渲染
+func toGeneric(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out any
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// writeYAMLValue 输出 YAML，字符串统一使用双引号以避免歧义，非普通标识符的键同样加引号
+func writeYAMLValue(b *strings.Builder, v any, indent int) {
+	pad := strings.Repeat("  ", indent)
+	switch val := v.(type) {
+	case map[string]any:
+		if len(val) == 0 {
+			b.WriteString(pad + "{}\n")
+			return
+		}
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			child := val[k]
+			if isYAMLScalar(child) {
+				b.WriteString(pad + yamlKey(k) + ": " + yamlScalar(child) + "\n")
+				continue
+			}
+			b.WriteString(pad + yamlKey(k) + ":\n")
+			writeYAMLValue(b, child, indent+1)
+		}
+	case []any:
+		if len(val) == 0 {
+			b.WriteString(pad + "[]\n")
+			return
+		}
+		for _, item := range val {
+			if isYAMLScalar(item) {
+				b.WriteString(pad + "- " + yamlScalar(item) + "\n")
+				continue
+			}
+			b.WriteString(pad + "-\n")
+			writeYAMLValue(b, item, indent+1)
+		}
+	default:
+		b.WriteString(pad + yamlScalar(val) + "\n")
+	}
+}
+
+// yamlKey 返回映射键的写法：由字母、数字与 _ . / - 组成且不以 - 开头的键原样输出，
+// 其他键（空串、含 : # 空格等、数字或 true/null 等会被解析为其他类型的键）与字符串值一样加双引号
+func yamlKey(k string) string {
+	if k == "" || k[0] == '-' || (k[0] >= '0' && k[0] <= '9') {
+		return strconv.Quote(k)
+	}
+	for _, r := range k {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("_./-", r)) {
+			return strconv.Quote(k)
+		}
+	}
+	switch strings.ToLower(k) {
+	case "true", "false", "null", "yes", "no", "on", "off", "y", "n":
+		return strconv.Quote(k)
+	}
+	return k
+}
+
+func isYAMLScalar(v any) bool {
+	switch val := v.(type) {
+	case map[string]any:
+		return len(val) == 0
+	case []any:
+		return len(val) == 0
+	default:
+		return true
+	}
+}
+
+func yamlScalar(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return strconv.Quote(val)
+	case bool:
+		return strconv.FormatBool(val)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case map[string]any:
+		return "{}"
+	case []any:
+		return "[]"
+	default:
+		return strconv.Quote(fmt.Sprint(val))
+	}
+}
+
+// renderText 输出简洁的文本摘要：首行为状态与消息，随后逐行列出结果字段
+func renderText(resp *Response) string {
+	var b strings.Builder
+	b.WriteString(resp.Status + ": " + resp.Message + "\n")
+	if resp.Code != "" {
+		b.WriteString("  code: " + resp.Code + "\n")
+	}
+	keys := make([]string, 0, len(resp.Result))
+	for k := range resp.Result {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := resp.Result[k]
+		if s, ok := v.(string); ok {
+			b.WriteString("  " + k + ": " + s + "\n")
+			continue
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			data = []byte(fmt.Sprint(v))
+		}
+		b.WriteString("  " + k + ": " + string(data) + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestYAMLKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"cert_id", "cert_id"},
+		{"managed-by", "managed-by"},
+		{"kubernetes.io/name", "kubernetes.io/name"},
+		{"", `""`},
+		{"a:b", `"a:b"`},
+		{"a #b", `"a #b"`},
+		{"-x", `"-x"`},
+		{"8080", `"8080"`},
+		{"null", `"null"`},
+		{"True", `"True"`},
+	}
+	for _, tt := range tests {
+		if got := yamlKey(tt.key); got != tt.want {
+			t.Errorf("yamlKey(%q) = %s, want %s", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestWriteYAMLValueQuotesKeys(t *testing.T) {
+	var b strings.Builder
+	writeYAMLValue(&b, map[string]any{
+		"labels": map[string]any{"team:edge": "a", "env": "prod"},
+		"-":      []any{},
+	}, 0)
+	want := `"-": []
+labels:
+  env: "prod"
+  "team:edge": "a"
+`
+	if b.String() != want {
+		t.Errorf("writeYAMLValue() =\n%s\nwant\n%s", b.String(), want)
+	}
+}
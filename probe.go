@@ -0,0 +1,103 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// 探测 TLS 握手的默认超时
+const defaultProbeTimeout = 5 * time.Second
+
+// Probe 以指定 SNI 与网关的数据面完成一次 TLS 握手，比较实际下发的叶子证书指纹与期望指纹，
+// 用于确认网关确实在使用新证书，而不仅是 Admin API 接受了写入。
+// 只比较指纹，不校验证书链，因此自签证书与内部 CA 同样适用
+func Probe(cfg map[string]any) (*Response, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config cannot be nil")
+	}
+	host, ok := cfg["probe_host"].(string)
+	if !ok || host == "" {
+		return nil, fmt.Errorf("probe_host is required and must be a string")
+	}
+	// 未写端口时默认 443
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, "443")
+	}
+	sni, _ := cfg["sni"].(string)
+	if sni == "" {
+		return nil, fmt.Errorf("sni is required and must be a string")
+	}
+	// 兼容 openssl 输出的 AB:CD:... 形式
+	expected, _ := cfg["fingerprint"].(string)
+	expected = strings.ToLower(strings.ReplaceAll(expected, ":", ""))
+	if certStr, _ := cfg["cert"].(string); certStr != "" {
+		sha, err := GetSHA256(certStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get SHA256 of cert: %w", err)
+		}
+		expected = sha
+	}
+	if expected == "" {
+		return nil, fmt.Errorf("cert or fingerprint is required")
+	}
+	timeoutMS, err := intParam(cfg, "probe_timeout_ms", int(defaultProbeTimeout/time.Millisecond))
+	if err != nil {
+		return nil, err
+	}
+	if timeoutMS <= 0 {
+		return nil, fmt.Errorf("probe_timeout_ms must be positive")
+	}
+	timeout := time.Duration(timeoutMS) * time.Millisecond
+
+	start := time.Now()
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", host, &tls.Config{
+		ServerName:         sni,
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return nil, fmt.Errorf("TLS probe to %s timed out after %s", host, timeout)
+		}
+		return nil, fmt.Errorf("TLS probe to %s (sni %s) failed: %w", host, sni, err)
+	}
+	defer conn.Close()
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("TLS probe to %s returned no certificate", host)
+	}
+	leaf := certs[0]
+	sum := sha256.Sum256(leaf.Raw)
+	served := hex.EncodeToString(sum[:])
+
+	result := map[string]any{
+		"probe_host":           host,
+		"sni":                  sni,
+		"match":                served == expected,
+		"served_fingerprint":   served,
+		"expected_fingerprint": expected,
+		"subject":              leaf.Subject.String(),
+		"dns_names":            leaf.DNSNames,
+		"not_after":            leaf.NotAfter.UTC().Format(time.RFC3339),
+		"handshake_ms":         float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if served != expected {
+		return &Response{
+			Status:  "error",
+			Message: fmt.Sprintf("gateway is serving a different certificate for %s", sni),
+			Result:  result,
+		}, nil
+	}
+	return &Response{
+		Status:  "success",
+		Message: "Gateway is serving the expected certificate",
+		Result:  result,
+	}, nil
+}
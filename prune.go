@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// certCreated 获取 SSL 对象的创建时间：优先读取 APISIX 的 create_time，退回读取 not-before 标签
+func certCreated(value map[string]any) (time.Time, string, bool) {
+	if ts, ok := value["create_time"].(float64); ok && ts > 0 {
+		return time.Unix(int64(ts), 0), "create_time", true
+	}
+	if labels, ok := value["labels"].(map[string]any); ok {
+		if s, ok := labels["not-before"].(string); ok {
+			if t, err := time.Parse("2006-01-02", s); err == nil {
+				return t, "label", true
+			}
+		}
+	}
+	return time.Time{}, "", false
+}
+
+// parseActiveDomains 解析 active_domains：域名集合数组（[["a.com","b.com"], ...]），
+// 也接受单个字符串数组作为一个集合
+func parseActiveDomains(v any) ([][]string, error) {
+	if v == nil {
+		return nil, nil
+	}
+	list, ok := v.([]any)
+	if !ok {
+		return nil, fmt.Errorf("active_domains must be an array")
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+	if _, flat := list[0].(string); flat {
+		set, err := parseDomains(v)
+		if err != nil {
+			return nil, renameField(err, "domain", "active_domains")
+		}
+		return [][]string{set}, nil
+	}
+	sets := make([][]string, 0, len(list))
+	for i, item := range list {
+		set, err := parseDomains(item)
+		if err != nil {
+			return nil, renameField(err, "domain", fmt.Sprintf("active_domains[%d]", i))
+		}
+		sets = append(sets, set)
+	}
+	return sets, nil
+}
+
+// Prune 删除超过 max_age_days 的托管证书。age_by 为 not_after（默认，按过期时间）
+// 或 created（按创建时间）；snis 与 active_domains 中任一集合一致的证书始终保留
+func Prune(cfg map[string]any) (*Response, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config cannot be nil")
+	}
+	if cfg["max_age_days"] == nil {
+		return nil, fieldErrorf("max_age_days", "is required")
+	}
+	maxAgeDays, err := nonNegativeIntParam(cfg, "max_age_days", 0)
+	if err != nil {
+		return nil, err
+	}
+	ageBy, _ := cfg["age_by"].(string)
+	if ageBy == "" {
+		ageBy = "not_after"
+	}
+	if ageBy != "not_after" && ageBy != "created" {
+		return nil, fmt.Errorf("unsupported age_by: %s", ageBy)
+	}
+	active, err := parseActiveDomains(cfg["active_domains"])
+	if err != nil {
+		return nil, err
+	}
+	dryRun, _ := cfg["dry_run"].(bool)
+	a, err := backendFromParams(cfg)
+	if err != nil {
+		return nil, err
+	}
+	certServer, err := a.listCertFromApisix()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list certs from Apisix: %w", err)
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -maxAgeDays)
+	pruned := make([]map[string]any, 0)
+	kept := make([]map[string]any, 0)
+	failed := 0
+	for _, cert := range certServer {
+		value, ok := cert["value"].(map[string]any)
+		if !ok || !isManagedCert(value) {
+			continue
+		}
+		id, _ := value["id"].(string)
+		snis, _ := snisFromValue(value)
+		item := map[string]any{"cert_id": id, "snis": snis}
+
+		var date time.Time
+		var source string
+		if ageBy == "created" {
+			date, source, ok = certCreated(value)
+		} else {
+			date, source, ok = certNotAfter(value)
+		}
+		if !ok {
+			item["reason"] = "date unknown"
+			kept = append(kept, item)
+			continue
+		}
+		item["date"] = date.UTC().Format(time.RFC3339)
+		item["source"] = source
+		if !date.Before(cutoff) {
+			continue
+		}
+		isActive := false
+		for _, set := range active {
+			if compareSlices(snis, set).Relation == relationEqual {
+				isActive = true
+				break
+			}
+		}
+		if isActive {
+			item["reason"] = "matches active_domains"
+			kept = append(kept, item)
+			continue
+		}
+		if !dryRun {
+			if _, err := a.DeleteCertFromApisix(id); err != nil {
+				item["error"] = err.Error()
+				failed++
+				kept = append(kept, item)
+				continue
+			}
+		}
+		pruned = append(pruned, item)
+	}
+
+	status, message := "success", "Certificates pruned successfully"
+	if dryRun {
+		message = "Dry run: no certificates were deleted"
+	}
+	if failed > 0 {
+		status = "partial"
+		message = fmt.Sprintf("%d certificate(s) failed to delete", failed)
+	}
+	result := map[string]any{
+		"dry_run": dryRun,
+		"pruned":  pruned,
+		"kept":    kept,
+		"count":   len(pruned),
+	}
+	// dry_run 时不删除任何证书，deleted 为空
+	deleted := make([]string, 0, len(pruned))
+	if !dryRun {
+		for _, item := range pruned {
+			deleted = append(deleted, item["cert_id"].(string))
+		}
+	}
+	result["deleted"] = deleted
+	attachDiagnostics(a, result)
+	return &Response{
+		Status:  status,
+		Message: message,
+		Result:  result,
+	}, nil
+}
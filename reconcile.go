@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 域名覆盖状态
+const (
+	coverageServed        = "served"
+	coverageUnmanaged     = "served_by_unmanaged"
+	coverageUnserved      = "unserved"
+	coverageMatchExact    = "exact"
+	coverageMatchWildcard = "wildcard"
+)
+
+// ReconcileReport 按域名交叉比对请求的 domain 与网关上全部 SSL 对象的 snis，只读：
+// served 表示由托管证书覆盖，served_by_unmanaged 表示只由非托管证书覆盖，unserved 表示没有证书覆盖。
+// 精确匹配优先于通配匹配，与 APISIX 选择证书的规则一致；禁用的证书不计入覆盖
+func ReconcileReport(cfg map[string]any) (*Response, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config cannot be nil")
+	}
+	domain, err := parseDomains(cfg["domain"])
+	if err != nil {
+		return nil, err
+	}
+	a, err := backendFromParams(cfg)
+	if err != nil {
+		return nil, err
+	}
+	certServer, err := a.listCertFromApisix()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list certs from Apisix: %w", err)
+	}
+
+	domains := make(map[string]any, len(domain))
+	summary := map[string]int{coverageServed: 0, coverageUnmanaged: 0, coverageUnserved: 0}
+	for _, d := range domain {
+		entry := coverageOf(d, certServer)
+		summary[entry["status"].(string)]++
+		domains[d] = entry
+	}
+	result := map[string]any{
+		"domains": domains,
+		"summary": summary,
+		"scanned": len(certServer),
+	}
+	attachDiagnostics(a, result)
+	return &Response{
+		Status:  "success",
+		Message: "Reconcile report generated successfully",
+		Result:  result,
+	}, nil
+}
+
+// coverageOf 计算单个域名的覆盖情况：先收集精确匹配的证书，没有时再收集通配匹配的证书
+func coverageOf(domain string, certServer []map[string]any) map[string]any {
+	var exact, wildcard []map[string]any
+	for _, cert := range certServer {
+		value, ok := cert["value"].(map[string]any)
+		if !ok || !certEnabled(value) {
+			continue
+		}
+		snis, _ := snisFromValue(value)
+		for _, sni := range snis {
+			if strings.EqualFold(sni, domain) {
+				exact = append(exact, value)
+				break
+			}
+			if sniMatchesHost(sni, domain) {
+				wildcard = append(wildcard, value)
+				break
+			}
+		}
+	}
+	entry := map[string]any{"status": coverageUnserved}
+	matched, match := exact, coverageMatchExact
+	if len(matched) == 0 {
+		matched, match = wildcard, coverageMatchWildcard
+	}
+	if len(matched) == 0 {
+		return entry
+	}
+	managedIDs := make([]string, 0)
+	unmanagedIDs := make([]string, 0)
+	for _, value := range matched {
+		id, _ := value["id"].(string)
+		if isManagedCert(value) {
+			managedIDs = append(managedIDs, id)
+		} else {
+			unmanagedIDs = append(unmanagedIDs, id)
+		}
+	}
+	entry["match"] = match
+	entry["cert_ids"] = managedIDs
+	entry["unmanaged_ids"] = unmanagedIDs
+	if len(managedIDs) > 0 {
+		entry["status"] = coverageServed
+	} else {
+		entry["status"] = coverageUnmanaged
+	}
+	return entry
+}
@@ -0,0 +1,40 @@
+package main
+
+// retryInfo 记录一次发生过重试的 API 调用：总尝试次数与每次失败尝试的错误摘要
+type retryInfo struct {
+	Operation string   `json:"operation"`
+	Attempts  int      `json:"attempts"`
+	Errors    []string `json:"errors"`
+}
+
+// retryRecord 保存最近一次调用的重试情况（未重试时为 nil），Auth 以值传递时通过指针共享
+type retryRecord struct {
+	last *retryInfo
+}
+
+// retryError 为重试后仍然失败的调用错误，输出时在 Result 中附带 retry_info
+type retryError struct {
+	Info retryInfo
+	Err  error
+}
+
+func (e *retryError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *retryError) Unwrap() error {
+	return e.Err
+}
+
+// retryRecorder 由支持记录重试信息的后端实现
+type retryRecorder interface {
+	RetryInfo() *retryInfo
+}
+
+// RetryInfo 返回最近一次调用的重试情况，该调用未发生重试时为 nil
+func (a Auth) RetryInfo() *retryInfo {
+	if a.retry == nil {
+		return nil
+	}
+	return a.retry.last
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+)
+
+// rollbackBackup 在删除冲突证书前为其生成备份快照（格式与 backup 动作相同），
+// 设置 backup_file 时同时写入文件，供回滚或人工恢复使用
+func rollbackBackup(certServer []map[string]any, ids []string, cfg map[string]any, result map[string]any) (map[string]any, error) {
+	want := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		want[id] = true
+	}
+	ssls := make([]map[string]any, 0, len(ids))
+	for _, cert := range certServer {
+		value, ok := cert["value"].(map[string]any)
+		if !ok {
+			continue
+		}
+		if id, _ := value["id"].(string); want[id] {
+			ssls = append(ssls, value)
+		}
+	}
+	backup := backupDocument(ssls)
+	if backupFile, _ := cfg["backup_file"].(string); backupFile != "" {
+		if err := writeBackupFile(backupFile, backup); err != nil {
+			return nil, err
+		}
+		result["backup_file"] = backupFile
+	}
+	return backup, nil
+}
+
+// rollbackDeleted 在删除旧证书中途失败时，从删除前的备份快照恢复已经删除的证书：
+// 设置 restore_on_rollback 时按原 id 写回。APISIX 通常不返回私钥，私钥取自 rollback_keys
+// （按证书 id 或指纹提供）或快照自带的 key；无法恢复的证书记为 unrecoverable 并附带 desc/snis
+func rollbackDeleted(a CertBackend, backup map[string]any, deleted []string, restore bool, keys map[string]string) map[string]any {
+	snapshot := make(map[string]map[string]any)
+	ssls, _ := backup["ssls"].([]map[string]any)
+	for _, value := range ssls {
+		if id, ok := value["id"].(string); ok {
+			snapshot[id] = value
+		}
+	}
+	restorer, canRestore := a.(sslRestorer)
+	restored := make([]string, 0)
+	unrecoverable := make([]map[string]any, 0)
+	for _, id := range deleted {
+		value := snapshot[id]
+		snis, _ := snisFromValue(value)
+		entry := map[string]any{"cert_id": id, "desc": value["desc"], "snis": snis}
+		certStr, _ := value["cert"].(string)
+		sha256, _ := GetSHA256(certStr)
+		keyStr := backupKey(value, keys, sha256)
+		switch {
+		case !restore:
+			entry["reason"] = "restore_on_rollback is not enabled"
+		case !canRestore:
+			entry["reason"] = "restoring deleted certs is not supported by this backend"
+		case certStr == "":
+			entry["reason"] = "the backup snapshot has no certificate for this object"
+		case keyStr == "":
+			entry["reason"] = "private key is not in the backup snapshot (APISIX does not return keys); supply it via rollback_keys keyed by cert id or fingerprint"
+		default:
+			restoreValue := make(map[string]any, len(value))
+			for k, v := range value {
+				restoreValue[k] = v
+			}
+			restoreValue["key"] = keyStr
+			if err := restorer.restoreSSL(id, restoreValue); err != nil {
+				entry["reason"] = fmt.Sprintf("failed to restore: %v", err)
+			} else {
+				restored = append(restored, id)
+				continue
+			}
+		}
+		unrecoverable = append(unrecoverable, entry)
+	}
+	return map[string]any{
+		"restored":      restored,
+		"unrecoverable": unrecoverable,
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// routeUpdater 由支持读取与更新单个路由的后端实现
+type routeUpdater interface {
+	getRoute(id string) (map[string]any, error)
+	patchRouteHosts(id string, hosts []string) error
+}
+
+// getRoute 读取指定 id 的路由
+func (a Auth) getRoute(id string) (map[string]any, error) {
+	res, err := a.ApisixAPI("/routes/"+url.PathEscape(id), map[string]interface{}{}, "GET")
+	if err != nil {
+		return nil, fmt.Errorf("failed to call Apisix API: %w", err)
+	}
+	value, ok := a.unwrapNode(res)["value"].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid response format: value not found")
+	}
+	return value, nil
+}
+
+// patchRouteHosts 以 PATCH 只更新路由的 hosts；APISIX 不允许 host 与 hosts 同时存在，
+// 同时清除旧的 host 字段（其值已由调用方并入 hosts）
+func (a Auth) patchRouteHosts(id string, hosts []string) error {
+	params := map[string]any{"hosts": hosts, "host": nil}
+	if _, err := a.ApisixAPI("/routes/"+url.PathEscape(id), params, "PATCH"); err != nil {
+		return fmt.Errorf("failed to call Apisix API: %w", err)
+	}
+	return nil
+}
+
+// missingHosts 返回 domain 中未被路由 hosts 覆盖（完全相同或通配匹配）的域名
+func missingHosts(hosts, domain []string) []string {
+	missing := make([]string, 0)
+	for _, d := range domain {
+		covered := false
+		for _, h := range hosts {
+			if sniMatchesHost(h, d) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			missing = append(missing, d)
+		}
+	}
+	return missing
+}
+
+// UploadBindAndRoute 上传绑定证书，并确保 route_id 指定路由的 hosts 包含证书的全部域名。
+// 路由在上传前先读取确认存在；路由更新失败时，本次新建的证书会被删除（复用或合并的已有证书保持不变）。
+// 结果按步骤给出 upload、route 与 rollback
+func UploadBindAndRoute(cfg map[string]any) (*Response, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config cannot be nil")
+	}
+	routeID, _ := cfg["route_id"].(string)
+	if routeID == "" {
+		return nil, fmt.Errorf("route_id is required and must be a string")
+	}
+	if mode, _ := cfg["mode"].(string); mode == "standalone" {
+		return nil, fmt.Errorf("upload_bind_and_route is not supported in standalone mode")
+	}
+	if backend, _ := cfg["backend"].(string); backend == "etcd" {
+		return nil, fmt.Errorf("upload_bind_and_route is not supported by the etcd backend")
+	}
+	if targets, _, err := bindTargets(cfg); err != nil {
+		return nil, err
+	} else if len(targets) > 0 {
+		return nil, fmt.Errorf("upload_bind_and_route does not support multiple targets")
+	}
+	a, err := backendFromParams(cfg)
+	if err != nil {
+		return nil, err
+	}
+	ru, ok := a.(routeUpdater)
+	if !ok {
+		return nil, fmt.Errorf("upload_bind_and_route is not supported by this backend")
+	}
+	route, err := ru.getRoute(routeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read route %s: %w", routeID, err)
+	}
+
+	p, result, err := prepareBind(cfg)
+	if err != nil {
+		return nil, err
+	}
+	upload, err := bindOnBackend(a, p, cfg, result)
+	if err != nil {
+		return nil, err
+	}
+	steps := map[string]any{"upload": upload.Result}
+	if upload.Status == "error" {
+		attachDiagnostics(a, steps)
+		return &Response{Status: "error", Message: upload.Message, Result: steps}, nil
+	}
+
+	hosts := routeHosts(route)
+	missing := missingHosts(hosts, p.Domain)
+	routeStep := map[string]any{"route_id": routeID, "added": missing, "changed": len(missing) > 0}
+	steps["route"] = routeStep
+	if len(missing) == 0 {
+		routeStep["hosts"] = hosts
+	} else {
+		hosts = normalizeDomains(append(hosts, missing...))
+		routeStep["hosts"] = hosts
+		if err := ru.patchRouteHosts(routeID, hosts); err != nil {
+			routeStep["changed"] = false
+			routeStep["error"] = err.Error()
+			steps["rollback"] = rollbackUpload(a, upload.Result)
+			attachDiagnostics(a, steps)
+			return &Response{
+				Status:  "error",
+				Message: fmt.Sprintf("failed to update hosts of route %s: %v", routeID, err),
+				Result:  steps,
+			}, nil
+		}
+	}
+	attachDiagnostics(a, steps)
+	resp := &Response{
+		Status:  upload.Status,
+		Message: "Certificate bound and route hosts updated successfully",
+		Result:  steps,
+	}
+	if len(missing) == 0 {
+		resp.Message = "Certificate bound, route hosts already include all domains"
+		// 证书复用且路由无需修改时沿用 no_change
+		resp.Code = upload.Code
+	}
+	return resp, nil
+}
+
+// rollbackUpload 在后续步骤失败时删除本次新建的证书；复用或合并的证书不是本次创建的，保持不变。
+// 上传时已删除了冲突的旧证书时同样保留新证书，否则这些域名将没有证书可用
+func rollbackUpload(a CertBackend, upload map[string]any) map[string]any {
+	action, _ := upload["action"].(string)
+	certID, _ := upload["cert_id"].(string)
+	rollback := map[string]any{"cert_id": certID, "removed_new_cert": false}
+	if action != "created" {
+		rollback["reason"] = fmt.Sprintf("cert was %s rather than created, left in place", action)
+		return rollback
+	}
+	if deleted, _ := upload["deleted_ids"].([]string); len(deleted) > 0 {
+		rollback["reason"] = "conflicting certs were already deleted, new cert kept so the domains stay served"
+		return rollback
+	}
+	if _, err := a.DeleteCertFromApisix(certID); err != nil {
+		rollback["error"] = err.Error()
+		return rollback
+	}
+	rollback["removed_new_cert"] = true
+	return rollback
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// paramSpec 对应 metadata.json 中的参数定义
+type paramSpec struct {
+	Name        string
+	Type        string
+	Items       string
+	Required    bool
+	Description string
+}
+
+// specsFromMeta 将元数据中的参数列表解析为 paramSpec
+func specsFromMeta(v any) []paramSpec {
+	list, _ := v.([]any)
+	specs := make([]paramSpec, 0, len(list))
+	for _, item := range list {
+		m, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		name, _ := m["name"].(string)
+		typ, _ := m["type"].(string)
+		items, _ := m["items"].(string)
+		required, _ := m["required"].(bool)
+		description, _ := m["description"].(string)
+		specs = append(specs, paramSpec{Name: name, Type: typ, Items: items, Required: required, Description: description})
+	}
+	return specs
+}
+
+// actionMeta 返回指定动作在元数据中的定义
+func actionMeta(action string) (map[string]any, bool) {
+	actions, _ := pluginMeta["actions"].([]any)
+	for _, item := range actions {
+		m, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		if name, _ := m["name"].(string); name == action {
+			return m, true
+		}
+	}
+	return nil, false
+}
+
+// actionSpecs 返回指定动作的参数定义；动作未在元数据中声明时 ok 为 false
+func actionSpecs(action string) ([]paramSpec, bool) {
+	m, ok := actionMeta(action)
+	if !ok {
+		return nil, false
+	}
+	return specsFromMeta(m["params"]), true
+}
+
+// resultSpecs 返回指定动作成功时 Result 中保证存在的字段
+func resultSpecs(action string) []paramSpec {
+	m, ok := actionMeta(action)
+	if !ok {
+		return nil
+	}
+	return specsFromMeta(m["result"])
+}
+
+// jsonSchemaType 将元数据类型（如 array|string）转换为 JSON Schema 的 type 取值
+func jsonSchemaType(typ string) any {
+	if !strings.Contains(typ, "|") {
+		return typ
+	}
+	return strings.Split(typ, "|")
+}
+
+// paramSchema 将单个参数定义转换为 JSON Schema 属性
+func paramSchema(spec paramSpec) map[string]any {
+	prop := map[string]any{"description": spec.Description}
+	if spec.Type != "" {
+		prop["type"] = jsonSchemaType(spec.Type)
+	}
+	if spec.Items != "" {
+		prop["items"] = map[string]any{"type": jsonSchemaType(spec.Items)}
+	}
+	return prop
+}
+
+// actionSchema 生成动作参数的 JSON Schema：动作参数按元数据标记必填，
+// 全局 config 参数全部列为可选属性，便于前端一次性生成完整表单
+func actionSchema(specs []paramSpec) map[string]any {
+	properties := map[string]any{}
+	required := make([]string, 0)
+	for _, spec := range specsFromMeta(pluginMeta["config"]) {
+		properties[spec.Name] = paramSchema(spec)
+	}
+	for _, spec := range specs {
+		properties[spec.Name] = paramSchema(spec)
+		if spec.Required {
+			required = append(required, spec.Name)
+		}
+	}
+	return map[string]any{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
+
+// resultSchema 生成动作 Result 的 JSON Schema，元数据中声明的字段全部为必有字段
+func resultSchema(specs []paramSpec) map[string]any {
+	properties := map[string]any{}
+	required := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		properties[spec.Name] = paramSchema(spec)
+		required = append(required, spec.Name)
+	}
+	return map[string]any{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
+
+// actionsWithSchemas 返回元数据中的动作列表，并在每个动作下附加 schema 字段；
+// 原有字段保持不变，兼容只读取 name/description/params 的调用方
+func actionsWithSchemas() []any {
+	actions, _ := pluginMeta["actions"].([]any)
+	out := make([]any, 0, len(actions))
+	for _, item := range actions {
+		m, ok := item.(map[string]any)
+		if !ok {
+			out = append(out, item)
+			continue
+		}
+		entry := make(map[string]any, len(m)+2)
+		for k, v := range m {
+			entry[k] = v
+		}
+		entry["schema"] = actionSchema(specsFromMeta(m["params"]))
+		entry["result_schema"] = resultSchema(specsFromMeta(m["result"]))
+		out = append(out, entry)
+	}
+	return out
+}
+
+// checkType 判断参数值是否符合元数据声明的类型，多个类型用 | 分隔（如 array|string）
+func checkType(v any, typ string) bool {
+	if strings.Contains(typ, "|") {
+		for _, t := range strings.Split(typ, "|") {
+			if checkType(v, t) {
+				return true
+			}
+		}
+		return false
+	}
+	switch typ {
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "array":
+		_, ok := v.([]any)
+		return ok
+	case "object":
+		_, ok := v.(map[string]any)
+		return ok
+	default:
+		// 未声明或未知类型不做限制
+		return true
+	}
+}
+
+// fieldError 描述单个参数的校验问题，Field 为字段路径（如 domain[2]、labels.env）
+type fieldError struct {
+	Field   string `json:"field"`
+	Problem string `json:"problem"`
+}
+
+// validationErrors 为一组字段校验错误，输出时放入 Result 的 errors 数组，便于调用方逐项处理
+type validationErrors []fieldError
+
+func (e validationErrors) Error() string {
+	parts := make([]string, len(e))
+	for i, fe := range e {
+		parts[i] = fe.Field + " " + fe.Problem
+	}
+	return strings.Join(parts, "; ")
+}
+
+// fieldErrorf 构造只含一个字段错误的 validationErrors
+func fieldErrorf(field, format string, args ...any) validationErrors {
+	return validationErrors{{Field: field, Problem: fmt.Sprintf(format, args...)}}
+}
+
+// renameField 将 err 中字段路径的前缀 from 替换为 to，用于嵌套参数（如 entries[0].domain[1]）；
+// 非 validationErrors 原样返回
+func renameField(err error, from, to string) error {
+	ve, ok := err.(validationErrors)
+	if !ok {
+		return err
+	}
+	renamed := make(validationErrors, len(ve))
+	for i, fe := range ve {
+		if strings.HasPrefix(fe.Field, from) {
+			fe.Field = to + strings.TrimPrefix(fe.Field, from)
+		}
+		renamed[i] = fe
+	}
+	return renamed
+}
+
+// validateParams 按元数据校验请求参数：动作参数检查必填与类型，全局 config 参数只检查类型
+// （admin_key 等连接参数是否必填取决于后端，由各动作自行校验）。
+// 收集全部问题后一并返回，而不是遇到第一个就停止
+func validateParams(action string, params map[string]any) error {
+	specs, ok := actionSpecs(action)
+	if !ok {
+		return nil
+	}
+	var errs validationErrors
+	for _, spec := range specs {
+		errs = append(errs, validateParam(spec, params, spec.Required)...)
+	}
+	for _, spec := range specsFromMeta(pluginMeta["config"]) {
+		errs = append(errs, validateParam(spec, params, false)...)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validateParam 校验单个参数；声明了 items 时逐个检查数组元素或对象的值
+func validateParam(spec paramSpec, params map[string]any, required bool) validationErrors {
+	v, ok := params[spec.Name]
+	if !ok || v == nil {
+		if required {
+			return fieldErrorf(spec.Name, "is required")
+		}
+		return nil
+	}
+	if !checkType(v, spec.Type) {
+		return fieldErrorf(spec.Name, "must be %s, got %T", spec.Type, v)
+	}
+	if spec.Items == "" {
+		return nil
+	}
+	var errs validationErrors
+	switch val := v.(type) {
+	case []any:
+		for i, item := range val {
+			if !checkType(item, spec.Items) {
+				errs = append(errs, fieldErrorf(fmt.Sprintf("%s[%d]", spec.Name, i), "must be %s, got %T", spec.Items, item)...)
+			}
+		}
+	case map[string]any:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if !checkType(val[k], spec.Items) {
+				errs = append(errs, fieldErrorf(spec.Name+"."+k, "must be %s, got %T", spec.Items, val[k])...)
+			}
+		}
+	}
+	return errs
+}
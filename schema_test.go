@@ -0,0 +1,113 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestActionsPopulateDeclaredResult 对每个声明了 result 的动作在 stub 上执行一次，
+// 确认成功响应填充了 metadata.json 中声明的全部字段且类型一致
+func TestActionsPopulateDeclaredResult(t *testing.T) {
+	stub := newAdminStub(t)
+	certPEM, keyPEM := testCert(t, "a.test", "www.a.test")
+	other, otherKey := testCert(t, "b.test")
+	stub.putSSL("100", map[string]any{"cert": other, "key": otherKey, "snis": []any{"b.test"}, "desc": "allinssl-other"})
+	stub.putSSL("200", map[string]any{"cert": other, "key": otherKey, "snis": []any{"e.test"}, "desc": "allinssl-doomed"})
+	stub.routes["r1"] = map[string]any{"id": "r1", "uri": "/", "hosts": []any{"x.test"}}
+	stub.consumers["jack"] = map[string]any{"username": "jack", "plugins": map[string]any{}}
+
+	// 数据面探测使用本地 TLS 服务，下发 upload_bind 上传的证书
+	pair, err := tls.X509KeyPair([]byte(certPEM), []byte(keyPEM))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataPlane := httptest.NewUnstartedServer(http.NotFoundHandler())
+	dataPlane.TLS = &tls.Config{Certificates: []tls.Certificate{pair}}
+	dataPlane.StartTLS()
+	defer dataPlane.Close()
+
+	backupFile := t.TempDir() + "/backup.json"
+	tests := []struct {
+		action string
+		params map[string]any
+	}{
+		{"upload_bind", map[string]any{"cert": certPEM, "key": keyPEM}},
+		{"upload_bind", map[string]any{"cert": certPEM, "key": keyPEM}}, // 复用已有证书
+		{"upload_bind", map[string]any{"cert": certPEM, "key": keyPEM, "mode": "standalone"}},
+		{"upload_bind", map[string]any{"cert": certPEM, "key": keyPEM, "server_address": []any{stub.URL, stub.URL + "/"}}},
+		{"upload_only", map[string]any{"cert": other, "key": otherKey, "domain": "c.test"}},
+		{"bind_only", map[string]any{"cert_id": "100", "domain": []any{"b.test", "d.test"}}},
+		{"replace_sni", map[string]any{"cert_id": "100", "domain": []any{"b.test"}}},
+		{"unbind_sni", map[string]any{"cert_id": "100", "domain": []any{"b.test"}, "delete_if_empty": true}},
+		{"get_cert_status", map[string]any{"cert_id": "1"}},
+		{"list_certs", nil},
+		{"list_expiring", map[string]any{"within_days": 365}},
+		{"count", nil},
+		{"whoami", nil},
+		{"search", map[string]any{"sni_contains": "a.test"}},
+		{"export_pem", map[string]any{"cert_id": "1"}},
+		{"diff", map[string]any{"entries": []any{map[string]any{"cert": certPEM, "domain": []any{"a.test"}}}}},
+		{"reconcile_report", map[string]any{"domain": []any{"a.test", "missing.test"}}},
+		{"backup", map[string]any{"backup_file": backupFile}},
+		{"restore", map[string]any{"backup_file": backupFile}},
+		{"prune", map[string]any{"max_age_days": 3650, "dry_run": true}},
+		{"prune", map[string]any{"max_age_days": 3650}},
+		{"consumer_mtls", map[string]any{"consumer_username": "jack", "mtls_plugin": "client-cert", "cert": certPEM}},
+		{"test_admin_key", map[string]any{"new_admin_key": "test-key"}},
+		{"probe", map[string]any{"probe_host": strings.TrimPrefix(dataPlane.URL, "https://"), "sni": "a.test", "cert": certPEM}},
+		{"delete_cert", map[string]any{"cert_id": "200"}},
+		{"upload_bind_and_route", map[string]any{"cert": certPEM, "key": keyPEM, "route_id": "r1"}},
+	}
+	covered := map[string]bool{}
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			resp := runAction(t, tt.action, stub.params(tt.params))
+			if resp.Status != "success" {
+				t.Fatalf("%s: status %s: %s", tt.action, resp.Status, resp.Message)
+			}
+			if gaps := resultGaps(resultSpecs(tt.action), resp.Result); len(gaps) > 0 {
+				t.Errorf("%s: %s", tt.action, strings.Join(gaps, "; "))
+			}
+			covered[tt.action] = true
+		})
+	}
+	for _, spec := range actionsWithSchemas() {
+		m := spec.(map[string]any)
+		name, _ := m["name"].(string)
+		if len(resultSpecs(name)) > 0 && !covered[name] {
+			t.Errorf("action %s declares a result but is not covered", name)
+		}
+	}
+}
+
+// resultGaps 列出成功响应的 result 中缺失、为 null 或类型与声明不符的字段
+func resultGaps(specs []paramSpec, result map[string]any) []string {
+	var gaps []string
+	for _, spec := range specs {
+		v, ok := result[spec.Name]
+		if !ok || v == nil {
+			gaps = append(gaps, spec.Name+" is missing")
+			continue
+		}
+		// 结果中是 Go 原生类型（[]string、int 等），按输出后的 JSON 形态判断类型
+		data, err := json.Marshal(v)
+		if err != nil {
+			gaps = append(gaps, fmt.Sprintf("%s cannot be encoded: %v", spec.Name, err))
+			continue
+		}
+		var decoded any
+		if err := json.Unmarshal(data, &decoded); err != nil || decoded == nil {
+			gaps = append(gaps, spec.Name+" is missing")
+			continue
+		}
+		if !checkType(decoded, spec.Type) {
+			gaps = append(gaps, fmt.Sprintf("%s must be %s, got %T", spec.Name, spec.Type, decoded))
+		}
+	}
+	return gaps
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Search 按条件在托管证书中查找：sni_contains 匹配任一 sni 的子串，subject_contains 匹配证书主题，
+// label 为 {key: value} 对象（value 为空字符串时只要求存在该 label）；多个条件同时满足才算匹配。
+// 过滤在本地对分页拉取的全部证书进行，all=true 时同时搜索非托管证书
+func Search(cfg map[string]any) (*Response, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config cannot be nil")
+	}
+	sniContains, _ := cfg["sni_contains"].(string)
+	sniContains = strings.ToLower(sniContains)
+	subjectContains, _ := cfg["subject_contains"].(string)
+	subjectContains = strings.ToLower(subjectContains)
+	labels, err := stringMapParam(cfg, "label")
+	if err != nil {
+		return nil, err
+	}
+	if sniContains == "" && subjectContains == "" && len(labels) == 0 {
+		return nil, fmt.Errorf("at least one of sni_contains, subject_contains or label is required")
+	}
+	all, _ := cfg["all"].(bool)
+
+	a, err := backendFromParams(cfg)
+	if err != nil {
+		return nil, err
+	}
+	certServer, err := a.listCertFromApisix()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list certs from Apisix: %w", err)
+	}
+	certs := make([]map[string]any, 0)
+	for _, cert := range certServer {
+		value, ok := cert["value"].(map[string]any)
+		if !ok || (!all && !isManagedCert(value)) {
+			continue
+		}
+		if sniContains != "" {
+			snis, _ := snisFromValue(value)
+			found := false
+			for _, sni := range snis {
+				if strings.Contains(strings.ToLower(sni), sniContains) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				continue
+			}
+		}
+		if !labelsMatch(value, labels) {
+			continue
+		}
+		subject := ""
+		if certStr, ok := value["cert"].(string); ok {
+			if leaf, err := parseLeaf(certStr); err == nil {
+				subject = leaf.Subject.String()
+			}
+		}
+		if subjectContains != "" && !strings.Contains(strings.ToLower(subject), subjectContains) {
+			continue
+		}
+		item := certSummary(value)
+		item["subject"] = subject
+		certs = append(certs, item)
+	}
+	result := map[string]any{
+		"count":   len(certs),
+		"certs":   certs,
+		"scanned": len(certServer),
+	}
+	attachDiagnostics(a, result)
+	return &Response{
+		Status:  "success",
+		Message: "Certificates searched successfully",
+		Result:  result,
+	}, nil
+}
+
+// labelsMatch 判断 SSL 对象的 labels 是否包含 want 中的全部键值，want 中值为空时只检查键存在
+func labelsMatch(value map[string]any, want map[string]string) bool {
+	labels, _ := value["labels"].(map[string]any)
+	for k, v := range want {
+		got, ok := labels[k]
+		if !ok {
+			return false
+		}
+		if s, _ := got.(string); v != "" && s != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// 收到中断信号后，留给进行中请求完成的时间
+const interruptGrace = 5 * time.Second
+
+var (
+	// interruptCtx 在收到 SIGINT/SIGTERM 时取消，批量操作据此停止发起新的操作
+	interruptCtx = context.Background()
+	// runCtx 在收到信号 interruptGrace 之后取消，用于中止仍未完成的 API 调用
+	runCtx = context.Background()
+)
+
+// signalHandled 为 true 表示已安装信号处理，嵌套调用 installSignalHandler 时不重复安装
+var signalHandled bool
+
+// installSignalHandler 安装 SIGINT/SIGTERM 处理：不再直接退出进程，
+// 而是取消上面的 context，让批量操作停止并输出已完成部分的汇总。
+// 只在批量写入（多目标部署、恢复）与 socket 模式期间安装，其余时间保持默认的立即退出；
+// 收到第一个信号后即恢复默认处理，再次发送信号会直接结束进程。
+// 返回的函数卸载处理并恢复 context，需在批量操作结束后调用
+func installSignalHandler() func() {
+	if signalHandled {
+		return func() {}
+	}
+	signalHandled = true
+	ictx, stopInterrupt := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	rctx, stopRun := context.WithCancel(context.Background())
+	interruptCtx, runCtx = ictx, rctx
+	go func() {
+		<-ictx.Done()
+		stopInterrupt()
+		select {
+		case <-time.After(interruptGrace):
+			debugf("interrupted, aborting in-flight requests")
+			stopRun()
+		case <-rctx.Done():
+		}
+	}()
+	return func() {
+		stopRun()
+		stopInterrupt()
+		signalHandled = false
+		// 保留已取消的 interruptCtx，调用方在卸载后仍可通过 interrupted() 得知本次是否被中断
+		runCtx = context.Background()
+	}
+}
+
+// interrupted 报告是否已收到中断信号
+func interrupted() bool {
+	return interruptCtx.Err() != nil
+}
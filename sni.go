@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ReplaceSNI 将指定证书的域名集合整体替换为新的 domain，证书内容与 id 保持不变。
+// 提供 key 时读取现有对象并以 PUT 整体写回；APISIX 不返回明文私钥，
+// 未提供 key 时改用 PATCH 只替换 snis
+func ReplaceSNI(cfg map[string]any) (*Response, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config cannot be nil")
+	}
+	certID, err := certIDParam(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if certID == "" {
+		return nil, fmt.Errorf("cert_id is required and must be a string")
+	}
+	domain, err := parseDomains(cfg["domain"])
+	if err != nil {
+		return nil, err
+	}
+	domain = normalizeDomains(domain)
+	keyStr, _ := cfg["key"].(string)
+
+	a, err := backendFromParams(cfg)
+	if err != nil {
+		return nil, err
+	}
+	value, err := findCert(a, certID)
+	if err != nil {
+		return nil, err
+	}
+	previous, _ := snisFromValue(value)
+	method, err := writeSNIs(a, certID, value, keyStr, domain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to replace snis of cert %s: %w", certID, err)
+	}
+	result := map[string]any{
+		"cert_id":       certID,
+		"snis":          domain,
+		"previous_snis": previous,
+		"method":        method,
+	}
+	attachDiagnostics(a, result)
+	return &Response{
+		Status:  "success",
+		Message: "Certificate snis replaced successfully",
+		Result:  result,
+	}, nil
+}
+
+// writeSNIs 将证书的 snis 整体写为 domain，返回使用的方法：提供 key 时以 PUT 整体写回
+// （保留原有证书与 desc），否则使用 PATCH 只更新 snis
+func writeSNIs(a CertBackend, certID string, value map[string]any, keyStr string, domain []string) (string, error) {
+	if keyStr != "" {
+		certStr, _ := value["cert"].(string)
+		if certStr == "" {
+			return "", fmt.Errorf("cert %s has no stored certificate to keep", certID)
+		}
+		desc, _ := value["desc"].(string)
+		if _, err := a.updateCertToApisix(certID, certStr, keyStr, desc, domain, nil); err != nil {
+			return "", err
+		}
+		return "PUT", nil
+	}
+	binder, ok := a.(sniBinder)
+	if !ok {
+		return "", fmt.Errorf("updating snis without key is not supported by this backend")
+	}
+	if err := binder.bindSNIs(certID, domain); err != nil {
+		return "", err
+	}
+	return "PATCH", nil
+}
+
+// findCertByFingerprint 按 SHA256 指纹查找 SSL 对象：托管证书比对标记，其余证书计算存储证书的指纹。
+// 指纹兼容 openssl 输出的 AB:CD:... 形式；匹配到多个对象时返回错误，需改用 cert_id
+func findCertByFingerprint(a CertBackend, fingerprint string) (map[string]any, error) {
+	fingerprint = strings.ToLower(strings.ReplaceAll(fingerprint, ":", ""))
+	certServer, err := a.listCertFromApisix()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list certs from Apisix: %w", err)
+	}
+	var found map[string]any
+	for _, cert := range certServer {
+		value, ok := cert["value"].(map[string]any)
+		if !ok {
+			continue
+		}
+		match := isManagedCert(value) && managedMark(value) == managedPrefix+fingerprint
+		if !match {
+			if certStr, ok := value["cert"].(string); ok {
+				if sha256, err := GetSHA256(certStr); err == nil && sha256 == fingerprint {
+					match = true
+				}
+			}
+		}
+		if !match {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("multiple certs match fingerprint %s, use cert_id instead", fingerprint)
+		}
+		found = value
+	}
+	if found == nil {
+		return nil, fmt.Errorf("no cert matches fingerprint %s", fingerprint)
+	}
+	return found, nil
+}
+
+// UnbindSNI 从证书的 snis 中移除 domain，证书本身保留。
+// 证书由 cert_id 或 fingerprint 指定；移除后 snis 为空时默认拒绝，delete_if_empty=true 时删除该对象
+func UnbindSNI(cfg map[string]any) (*Response, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config cannot be nil")
+	}
+	certID, err := certIDParam(cfg)
+	if err != nil {
+		return nil, err
+	}
+	fingerprint, _ := cfg["fingerprint"].(string)
+	if certID == "" && fingerprint == "" {
+		return nil, fmt.Errorf("cert_id or fingerprint is required")
+	}
+	domain, err := parseDomains(cfg["domain"])
+	if err != nil {
+		return nil, err
+	}
+	domain = normalizeDomains(domain)
+	keyStr, _ := cfg["key"].(string)
+	deleteIfEmpty, _ := cfg["delete_if_empty"].(bool)
+
+	a, err := backendFromParams(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var value map[string]any
+	if certID != "" {
+		value, err = findCert(a, certID)
+	} else {
+		value, err = findCertByFingerprint(a, fingerprint)
+	}
+	if err != nil {
+		return nil, err
+	}
+	certID, _ = value["id"].(string)
+	previous, _ := snisFromValue(value)
+
+	remove := make(map[string]bool, len(domain))
+	for _, d := range domain {
+		remove[d] = true
+	}
+	remaining := make([]string, 0, len(previous))
+	for _, sni := range previous {
+		if remove[strings.ToLower(sni)] {
+			delete(remove, strings.ToLower(sni))
+			continue
+		}
+		remaining = append(remaining, sni)
+	}
+	for _, d := range domain {
+		if remove[d] {
+			return nil, fmt.Errorf("domain %s is not bound to cert %s", d, certID)
+		}
+	}
+
+	result := map[string]any{
+		"cert_id":       certID,
+		"removed":       domain,
+		"previous_snis": previous,
+		"snis":          remaining,
+		"deleted":       false,
+	}
+	if len(remaining) == 0 {
+		if !deleteIfEmpty {
+			return nil, fmt.Errorf("removing %s would leave cert %s without snis; set delete_if_empty to delete it", strings.Join(domain, ", "), certID)
+		}
+		if _, err := a.DeleteCertFromApisix(certID); err != nil {
+			return nil, fmt.Errorf("failed to delete cert %s: %w", certID, err)
+		}
+		result["deleted"] = true
+		result["method"] = "DELETE"
+	} else {
+		method, err := writeSNIs(a, certID, value, keyStr, remaining)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update snis of cert %s: %w", certID, err)
+		}
+		result["method"] = method
+	}
+	attachDiagnostics(a, result)
+	return &Response{
+		Status:  "success",
+		Message: "Certificate sni unbound successfully",
+		Result:  result,
+	}, nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+// serveSocket 在 Unix domain socket 上依次处理 count 个请求：每个连接发送一个 JSON 请求，
+// 插件写回响应后关闭连接。socket 文件仅当前用户可访问，密钥不经过可能被其他进程共享的管道
+func serveSocket(path string, count int) error {
+	if count <= 0 {
+		return fmt.Errorf("socket-count must be positive")
+	}
+	// 清理上次异常退出残留的 socket 文件，其他类型的文件不覆盖
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to remove stale socket: %w", err)
+		}
+	}
+	// 创建 socket 文件时即限制权限；Chmod 作为不支持 umask 的平台上的补充
+	restore := restrictUmask()
+	ln, err := net.Listen("unix", path)
+	restore()
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	defer ln.Close()
+	if err := os.Chmod(path, 0600); err != nil {
+		return fmt.Errorf("failed to restrict socket permissions: %w", err)
+	}
+	// 收到中断信号时关闭监听，结束等待中的 Accept
+	go func() {
+		<-interruptCtx.Done()
+		ln.Close()
+	}()
+	for i := 0; i < count; i++ {
+		conn, err := ln.Accept()
+		if err != nil {
+			if interrupted() {
+				return nil
+			}
+			return fmt.Errorf("failed to accept connection: %w", err)
+		}
+		serveConn(conn)
+	}
+	return nil
+}
+
+// 读取单个请求的最长时间，避免连接后不发送请求的客户端阻塞后续请求
+const socketReadTimeout = 30 * time.Second
+
+// serveConn 读取连接上的一个 JSON 请求并写回响应；
+// 按 JSON 值边界读取，客户端无需半关闭连接即可收到响应。
+// 与 stdin 相同接受 gzip 压缩的请求，读到 gzip 流结尾即视为请求完整
+func serveConn(conn net.Conn) {
+	defer conn.Close()
+	responseOut = conn
+	defer func() { responseOut = os.Stdout }()
+	conn.SetReadDeadline(time.Now().Add(socketReadTimeout))
+	input, err := readConnRequest(bufio.NewReader(conn))
+	if err != nil {
+		outputError("读取输入失败", err)
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+	handleRequest(input)
+}
+
+// readConnRequest 读取一个请求：gzip 数据只读取一个 gzip 成员并解压，否则读取一个 JSON 值
+func readConnRequest(br *bufio.Reader) ([]byte, error) {
+	if magic, _ := br.Peek(len(gzipMagic)); bytes.Equal(magic, gzipMagic) {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress gzip request: %w", err)
+		}
+		// 不等待后续 gzip 成员，否则客户端不关闭连接时读取会一直阻塞
+		zr.Multistream(false)
+		return readGzip(zr)
+	}
+	var input json.RawMessage
+	if err := json.NewDecoder(br).Decode(&input); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
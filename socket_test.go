@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+)
+
+// TestServeConnGzip socket 连接上的请求与 stdin 一样可以 gzip 压缩，客户端无需关闭写端
+func TestServeConnGzip(t *testing.T) {
+	request := []byte(`{"action": "list_actions", "params": {}}`)
+	compressed, err := gzipBody(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		input []byte
+	}{
+		{"plain", request},
+		{"gzip", compressed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, server := net.Pipe()
+			defer client.Close()
+			done := make(chan struct{})
+			go func() {
+				serveConn(server)
+				close(done)
+			}()
+			go client.Write(tt.input)
+			var resp Response
+			if err := json.NewDecoder(client).Decode(&resp); err != nil {
+				t.Fatalf("read response: %v", err)
+			}
+			if resp.Status != "success" {
+				t.Errorf("response = %s %s, want success", resp.Status, resp.Message)
+			}
+			<-done
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// standaloneHeader 标记由插件生成的 ssls 片段文件；只有带该标记的文件会被合并改写，
+// 其他已存在的文件（如完整的 apisix.yaml）不会被覆盖
+const standaloneHeader = "# allinssl ssls fragment: merge the entries below into the ssls section of apisix.yaml (before #END)"
+
+// standaloneSSL 组装写入 Standalone 配置的 SSL 对象，字段与 Admin API 上传时一致：
+// labels（含有效期与托管标记）、validity_start/end、备用证书与 extra_fields 都来自 extra；
+// managed_by_field 为 labels 时不写 desc
+func standaloneSSL(id, cert, key, note string, snis []string, extra map[string]any) map[string]any {
+	ssl := map[string]any{
+		"id":   id,
+		"snis": snis,
+		"cert": cert,
+		"key":  key,
+	}
+	if desc := storedDesc(note); desc != "" {
+		ssl["desc"] = desc
+	}
+	for k, v := range extra {
+		ssl[k] = v
+	}
+	return ssl
+}
+
+// renderStandaloneEntry 将 SSL 对象渲染为 ssls 列表中的一项：id 写在首行以便合并时识别，
+// cert 与 key 以块标量输出，其余字段复用 yaml 输出格式
+func renderStandaloneEntry(ssl map[string]any) (string, error) {
+	rest := make(map[string]any, len(ssl))
+	for k, v := range ssl {
+		if k != "id" && k != "cert" && k != "key" {
+			rest[k] = v
+		}
+	}
+	generic, err := toGeneric(rest)
+	if err != nil {
+		return "", err
+	}
+	id, _ := ssl["id"].(string)
+	cert, _ := ssl["cert"].(string)
+	key, _ := ssl["key"].(string)
+	var b strings.Builder
+	b.WriteString("  - id: " + yamlScalar(id) + "\n")
+	writeYAMLValue(&b, generic, 2)
+	writeYAMLBlock(&b, "cert", cert)
+	writeYAMLBlock(&b, "key", key)
+	return b.String(), nil
+}
+
+// writeYAMLBlock 以块标量（|）形式写入多行 PEM 内容
+func writeYAMLBlock(b *strings.Builder, name, value string) {
+	b.WriteString("    " + name + ": |\n")
+	for _, line := range strings.Split(strings.TrimRight(value, "\n"), "\n") {
+		b.WriteString("      " + strings.TrimRight(line, "\r") + "\n")
+	}
+}
+
+// splitStandaloneFragment 将插件生成的片段拆分为各 SSL 项，返回按出现顺序排列的 id 与对应文本
+func splitStandaloneFragment(content string) ([]string, map[string]string, error) {
+	var ids []string
+	entries := map[string]string{}
+	current := ""
+	for _, line := range strings.SplitAfter(content, "\n") {
+		switch {
+		case strings.HasPrefix(line, "  - id: "):
+			var id string
+			if err := json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, "  - id: "))), &id); err != nil {
+				return nil, nil, fmt.Errorf("invalid ssl entry %q", strings.TrimSpace(line))
+			}
+			if _, ok := entries[id]; !ok {
+				ids = append(ids, id)
+			}
+			current = id
+			entries[id] = line
+		case strings.HasPrefix(line, "    ") && current != "":
+			entries[current] += line
+		}
+	}
+	return ids, entries, nil
+}
+
+// mergeStandaloneFragment 将 entry 合并进 outputFile 中已有的片段：同 id 的项被替换，其余保留；
+// 文件不存在时新建。返回写入的完整片段以及是否替换了已有项
+func mergeStandaloneFragment(outputFile, id, entry string) (string, bool, error) {
+	var ids []string
+	entries := map[string]string{}
+	data, err := os.ReadFile(outputFile)
+	switch {
+	case err == nil:
+		if !strings.HasPrefix(string(data), standaloneHeader+"\n") {
+			return "", false, fmt.Errorf("output_file %s exists and was not written by this plugin; refusing to overwrite it, point output_file at a separate fragment file", outputFile)
+		}
+		if ids, entries, err = splitStandaloneFragment(string(data)); err != nil {
+			return "", false, fmt.Errorf("failed to parse output_file %s: %w", outputFile, err)
+		}
+	case errors.Is(err, os.ErrNotExist):
+	default:
+		return "", false, fmt.Errorf("failed to read output_file: %w", err)
+	}
+	_, replaced := entries[id]
+	if !replaced {
+		ids = append(ids, id)
+	}
+	entries[id] = entry
+	var b strings.Builder
+	b.WriteString(standaloneHeader + "\nssls:\n")
+	for _, existing := range ids {
+		b.WriteString(entries[existing])
+	}
+	return b.String(), replaced, nil
+}
+
+// uploadStandalone 将 SSL 对象渲染为 apisix.yaml 的 ssls 片段：设置 output_file 时合并写入该片段文件，
+// 否则放入返回结果。片段不含 #END，由部署流程合并进 apisix.yaml
+func uploadStandalone(p *bindPlan, outputFile string, result map[string]interface{}) (*Response, error) {
+	id := p.storeID()
+	entry, err := renderStandaloneEntry(standaloneSSL(id, p.Cert, p.Key, p.Note, p.Domain, p.Extra))
+	if err != nil {
+		return nil, fmt.Errorf("failed to render standalone config: %w", err)
+	}
+	result["message"] = "已生成配置"
+	result["action"] = "generated"
+	result["changed"] = true
+	result["cert_id"] = id
+	result["snis"] = p.Domain
+	result["deleted_ids"] = []string{}
+	if outputFile != "" {
+		fragment, replaced, err := mergeStandaloneFragment(outputFile, id, entry)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(outputFile, []byte(fragment), 0600); err != nil {
+			return nil, fmt.Errorf("failed to write output file: %w", err)
+		}
+		result["output_file"] = outputFile
+		result["replaced"] = replaced
+	} else {
+		result["yaml"] = "ssls:\n" + entry
+	}
+	return &Response{
+		Status:  "success",
+		Message: "Standalone config rendered successfully",
+		Result:  result,
+	}, nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUploadStandaloneFragment(t *testing.T) {
+	certPEM, keyPEM := testCert(t, "a.test")
+	tests := []struct {
+		name    string
+		params  map[string]any
+		want    []string
+		notWant []string
+	}{
+		{
+			"desc",
+			nil,
+			[]string{"ssls:\n  - id: ", "    desc: \"allinssl-", "      not-after: ", "    cert: |\n      -----BEGIN CERTIFICATE-----"},
+			[]string{"#END", "managed-by"},
+		},
+		{
+			"labels",
+			map[string]any{"managed_by_field": "labels", "labels": map[string]any{"env": "prod"}},
+			[]string{"      managed-by: \"allinssl-", "      env: \"prod\""},
+			[]string{"desc:"},
+		},
+		{
+			"extra fields",
+			map[string]any{"extra_fields": map[string]any{"ssl_protocols": []any{"TLSv1.3"}}},
+			[]string{"    ssl_protocols:\n      - \"TLSv1.3\""},
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := map[string]any{"cert": certPEM, "key": keyPEM, "mode": "standalone", "server_address": "http://127.0.0.1:9180", "admin_key": "test-key"}
+			for k, v := range tt.params {
+				params[k] = v
+			}
+			resp := runAction(t, "upload_bind", params)
+			if resp.Status != "success" {
+				t.Fatalf("status %s: %s", resp.Status, resp.Message)
+			}
+			yaml, _ := resp.Result["yaml"].(string)
+			for _, want := range tt.want {
+				if !strings.Contains(yaml, want) {
+					t.Errorf("fragment missing %q:\n%s", want, yaml)
+				}
+			}
+			for _, unwanted := range tt.notWant {
+				if strings.Contains(yaml, unwanted) {
+					t.Errorf("fragment contains %q:\n%s", unwanted, yaml)
+				}
+			}
+		})
+	}
+}
+
+func TestMergeStandaloneFragment(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "ssls.yaml")
+	steps := []struct {
+		id           string
+		entry        string
+		wantReplaced bool
+		wantIDs      []string
+	}{
+		{"a", "  - id: \"a\"\n    snis:\n      - \"a.test\"\n", false, []string{"a"}},
+		{"b", "  - id: \"b\"\n    snis:\n      - \"b.test\"\n", false, []string{"a", "b"}},
+		{"a", "  - id: \"a\"\n    snis:\n      - \"c.test\"\n", true, []string{"a", "b"}},
+	}
+	for _, step := range steps {
+		fragment, replaced, err := mergeStandaloneFragment(file, step.id, step.entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if replaced != step.wantReplaced {
+			t.Errorf("merge %s: replaced = %v, want %v", step.id, replaced, step.wantReplaced)
+		}
+		ids, entries, err := splitStandaloneFragment(fragment)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(ids, ",") != strings.Join(step.wantIDs, ",") {
+			t.Errorf("merge %s: ids = %v, want %v", step.id, ids, step.wantIDs)
+		}
+		if entries[step.id] != step.entry {
+			t.Errorf("merge %s: entry = %q, want %q", step.id, entries[step.id], step.entry)
+		}
+		if err := os.WriteFile(file, []byte(fragment), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestMergeStandaloneFragmentRefusesForeignFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "apisix.yaml")
+	if err := os.WriteFile(file, []byte("routes: []\n#END\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := mergeStandaloneFragment(file, "a", "  - id: \"a\"\n"); err == nil {
+		t.Fatal("mergeStandaloneFragment() overwrote a file it did not write")
+	}
+	data, _ := os.ReadFile(file)
+	if string(data) != "routes: []\n#END\n" {
+		t.Errorf("file changed: %q", data)
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// findCert 在证书列表中按 id 查找 SSL 对象，未找到时返回错误
+func findCert(a CertBackend, certID string) (map[string]any, error) {
+	certServer, err := a.listCertFromApisix()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list certs from Apisix: %w", err)
+	}
+	for _, cert := range certServer {
+		value, ok := cert["value"].(map[string]any)
+		if !ok {
+			continue
+		}
+		if id, _ := value["id"].(string); id == certID {
+			return value, nil
+		}
+	}
+	return nil, fmt.Errorf("cert %s not found", certID)
+}
+
+// routeHosts 读取路由的 host/hosts 字段
+func routeHosts(route map[string]any) []string {
+	hosts := make([]string, 0)
+	if host, ok := route["host"].(string); ok && host != "" {
+		hosts = append(hosts, host)
+	}
+	if list, ok := route["hosts"].([]any); ok {
+		for _, h := range list {
+			if s, ok := h.(string); ok && s != "" {
+				hosts = append(hosts, s)
+			}
+		}
+	}
+	return hosts
+}
+
+// sniMatchesHost 判断证书的 sni 是否能服务路由的 host：完全相同，或通配 sni 覆盖该 host
+func sniMatchesHost(sni, host string) bool {
+	sni, host = strings.ToLower(sni), strings.ToLower(host)
+	if sni == host {
+		return true
+	}
+	if strings.HasPrefix(sni, "*.") {
+		if i := strings.Index(host, "."); i > 0 && host[i:] == sni[1:] {
+			return true
+		}
+	}
+	return false
+}
+
+// certEnabled 判断 SSL 对象是否启用，APISIX 中 status 缺省为 1（启用）
+func certEnabled(value map[string]any) bool {
+	if status, ok := numericValue(value["status"]); ok {
+		return status == 1
+	}
+	return true
+}
+
+// GetCertStatus 返回证书的启用状态与有效期；设置 check_usage 时扫描路由，
+// 列出 host 与证书 snis 匹配的路由，用于判断删除证书是否会影响线上流量
+func GetCertStatus(cfg map[string]any) (*Response, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config cannot be nil")
+	}
+	certID, ok := cfg["cert_id"].(string)
+	if !ok || certID == "" {
+		return nil, fmt.Errorf("cert_id is required and must be a string")
+	}
+	a, err := backendFromParams(cfg)
+	if err != nil {
+		return nil, err
+	}
+	value, err := findCert(a, certID)
+	if err != nil {
+		return nil, err
+	}
+
+	enabled := certEnabled(value)
+	snis, _ := snisFromValue(value)
+	result := map[string]any{
+		"cert_id": certID,
+		"desc":    value["desc"],
+		"snis":    snis,
+		"enabled": enabled,
+		"managed": isManagedCert(value),
+	}
+	if notAfter, source, ok := certNotAfter(value); ok {
+		result["not_after"] = notAfter.UTC().Format(time.RFC3339)
+		result["days_left"] = int(time.Until(notAfter).Hours() / 24)
+		result["expired"] = time.Now().After(notAfter)
+		result["not_after_source"] = source
+	}
+	for _, field := range []string{"validity_start", "validity_end"} {
+		if ts, ok := numericValue(value[field]); ok {
+			result[field] = ts
+		}
+	}
+
+	if checkUsage, _ := cfg["check_usage"].(bool); checkUsage {
+		rl, ok := a.(routeLister)
+		if !ok {
+			return nil, fmt.Errorf("check_usage is not supported by this backend")
+		}
+		routes, err := rl.listRoutes()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list routes from Apisix: %w", err)
+		}
+		routeIDs := make([]string, 0)
+		for _, route := range routes {
+			rv, ok := route["value"].(map[string]any)
+			if !ok {
+				continue
+			}
+			id, _ := rv["id"].(string)
+		match:
+			for _, host := range routeHosts(rv) {
+				for _, sni := range snis {
+					if sniMatchesHost(sni, host) {
+						routeIDs = append(routeIDs, id)
+						break match
+					}
+				}
+			}
+		}
+		result["routes"] = routeIDs
+		result["in_use"] = len(routeIDs) > 0
+	}
+	attachDiagnostics(a, result)
+	return &Response{
+		Status:  "success",
+		Message: "Certificate status retrieved successfully",
+		Result:  result,
+	}, nil
+}
+
+// protectInUse 从待删除列表中剔除仍在服务路由的证书：存在路由的 host 由该证书的 snis 覆盖，
+// 而新绑定的 domain 不覆盖。被保留的证书 id 写入 result["protected"] 并附带告警
+func protectInUse(a CertBackend, ids []string, snisByID map[string][]string, domain []string, result map[string]any) ([]string, error) {
+	rl, ok := a.(routeLister)
+	if !ok {
+		return nil, fmt.Errorf("protect_in_use is not supported by this backend")
+	}
+	routes, err := rl.listRoutes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list routes from Apisix: %w", err)
+	}
+	// 收集新证书无法服务的路由 host
+	uncovered := make(map[string][]string)
+	for _, route := range routes {
+		rv, ok := route["value"].(map[string]any)
+		if !ok {
+			continue
+		}
+		routeID, _ := rv["id"].(string)
+		for _, host := range routeHosts(rv) {
+			covered := false
+			for _, d := range domain {
+				if sniMatchesHost(d, host) {
+					covered = true
+					break
+				}
+			}
+			if !covered {
+				uncovered[host] = append(uncovered[host], routeID)
+			}
+		}
+	}
+	kept := make([]string, 0, len(ids))
+	protected := make([]map[string]any, 0)
+	for _, id := range ids {
+		routeIDs := make([]string, 0)
+		for host, hostRoutes := range uncovered {
+			for _, sni := range snisByID[id] {
+				if sniMatchesHost(sni, host) {
+					routeIDs = append(routeIDs, hostRoutes...)
+					break
+				}
+			}
+		}
+		if len(routeIDs) == 0 {
+			kept = append(kept, id)
+			continue
+		}
+		sort.Strings(routeIDs)
+		protected = append(protected, map[string]any{"cert_id": id, "snis": snisByID[id], "routes": routeIDs})
+		addWarning(result, warnKeptInUse, fmt.Sprintf("kept cert %s: still serving routes %v that the new cert does not cover", id, routeIDs))
+	}
+	if len(protected) > 0 {
+		result["protected"] = protected
+	}
+	return kept, nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 告警代码：每条写入 result["warnings"] 的告警在 result["warning_codes"] 中有对应代码。
+// 设置全局 strict 时，以下告警都会被提升为 status=error 的响应，Code 为第一条告警的代码：
+//
+//	weak_cert            证书 RSA 密钥过短或使用 SHA-1 等弱签名算法
+//	domain_mismatch      请求的域名均不在证书 SAN 中
+//	chain_invalid        verify_chain 校验失败且设置了 chain_warn_only
+//	webhook_failed       部署完成但 webhook 通知发送失败
+//	fingerprint_conflict 已有证书 desc 与指纹一致但存储内容不同
+//	kept_in_use          protect_in_use 保留了仍在服务路由的冲突证书
+//	no_client_auth       consumer 证书不允许客户端认证
+//	collapse_failed      上传后删除重复副本失败
+//	extra_san            证书包含未在 domain 中请求的 DNS 名称
+//	unconfirmed          verify_before_delete 未能确认新证书生效，旧证书被保留
+//	ttl_ignored          请求了 ttl_seconds 但目标不支持，证书不会自动过期
+const (
+	warnWeakCert            = "weak_cert"
+	warnDomainMismatch      = "domain_mismatch"
+	warnChainInvalid        = "chain_invalid"
+	warnWebhookFailed       = "webhook_failed"
+	warnFingerprintConflict = "fingerprint_conflict"
+	warnKeptInUse           = "kept_in_use"
+	warnNoClientAuth        = "no_client_auth"
+	warnCollapseFailed      = "collapse_failed"
+	warnExtraSAN            = "extra_san"
+	warnUnconfirmed         = "unconfirmed"
+	warnTTLIgnored          = "ttl_ignored"
+)
+
+// strictMode 为 true 时任何告警都视为失败，供 CI 流水线使用
+var strictMode bool
+
+// addWarning 向返回结果的 warnings 列表追加一条告警，并在 warning_codes 中记录其代码
+func addWarning(result map[string]any, code, msg string) {
+	warnings, _ := result["warnings"].([]string)
+	result["warnings"] = append(warnings, msg)
+	codes, _ := result["warning_codes"].([]string)
+	result["warning_codes"] = append(codes, code)
+}
+
+// strictError 为 strict 模式下由告警提升而来的错误，携带告警代码
+type strictError struct {
+	Code string
+	Err  error
+}
+
+func (e *strictError) Error() string { return e.Err.Error() }
+
+func (e *strictError) Unwrap() error { return e.Err }
+
+// collectWarnings 收集结果中的全部告警及代码：除顶层外，递归进入嵌套的结果
+// （如 upload_bind_and_route 的 steps.upload、多目标部署的 servers.<地址>），
+// 各目标从同一初始结果继承的相同告警只计一次
+func collectWarnings(result map[string]any) ([]string, []string) {
+	var warnings, codes []string
+	seen := map[string]bool{}
+	var walk func(m map[string]any)
+	walk = func(m map[string]any) {
+		msgs, _ := m["warnings"].([]string)
+		mcodes, _ := m["warning_codes"].([]string)
+		for i, msg := range msgs {
+			code := "warning"
+			if i < len(mcodes) {
+				code = mcodes[i]
+			}
+			if key := code + "\x00" + msg; !seen[key] {
+				seen[key] = true
+				warnings = append(warnings, msg)
+				codes = append(codes, code)
+			}
+		}
+		// 按键排序遍历，使 Code 取到的第一条告警稳定
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if nested, ok := m[k].(map[string]any); ok {
+				walk(nested)
+			}
+		}
+	}
+	walk(result)
+	return warnings, codes
+}
+
+// applyStrict 在 strict 模式下将带告警的成功响应转换为错误响应，结果保留以便排查
+func applyStrict(resp *Response) *Response {
+	if !strictMode || resp.Status == "error" {
+		return resp
+	}
+	warnings, codes := collectWarnings(resp.Result)
+	if len(warnings) == 0 {
+		return resp
+	}
+	code := codes[0]
+	return &Response{
+		Status:  "error",
+		Code:    code,
+		Message: fmt.Sprintf("strict mode: %s", strings.Join(warnings, "; ")),
+		Result:  resp.Result,
+	}
+}
+
+// errorCode 返回错误链中 strictError 携带的告警代码，没有时返回空字符串
+func errorCode(err error) string {
+	var se *strictError
+	if errors.As(err, &se) {
+		return se.Code
+	}
+	return ""
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCollectWarnings(t *testing.T) {
+	tests := []struct {
+		name         string
+		result       map[string]any
+		wantWarnings []string
+		wantCodes    []string
+	}{
+		{"none", map[string]any{"cert_id": "1"}, nil, nil},
+		{
+			"top level",
+			map[string]any{"warnings": []string{"weak"}, "warning_codes": []string{warnWeakCert}},
+			[]string{"weak"},
+			[]string{warnWeakCert},
+		},
+		{
+			"route step",
+			map[string]any{"steps": map[string]any{
+				"upload": map[string]any{"warnings": []string{"extra"}, "warning_codes": []string{warnExtraSAN}},
+				"route":  map[string]any{"route_id": "r1"},
+			}},
+			[]string{"extra"},
+			[]string{warnExtraSAN},
+		},
+		{
+			"per target deduplicated",
+			map[string]any{
+				"warnings":      []string{"weak"},
+				"warning_codes": []string{warnWeakCert},
+				"servers": map[string]any{
+					"http://a": map[string]any{"warnings": []string{"weak", "kept"}, "warning_codes": []string{warnWeakCert, warnKeptInUse}},
+					"http://b": map[string]any{"warnings": []string{"weak"}, "warning_codes": []string{warnWeakCert}},
+				},
+			},
+			[]string{"weak", "kept"},
+			[]string{warnWeakCert, warnKeptInUse},
+		},
+		{
+			"missing code",
+			map[string]any{"warnings": []string{"legacy"}},
+			[]string{"legacy"},
+			[]string{"warning"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warnings, codes := collectWarnings(tt.result)
+			if !reflect.DeepEqual(warnings, tt.wantWarnings) || !reflect.DeepEqual(codes, tt.wantCodes) {
+				t.Errorf("collectWarnings() = %v %v, want %v %v", warnings, codes, tt.wantWarnings, tt.wantCodes)
+			}
+		})
+	}
+}
+
+func TestApplyStrict(t *testing.T) {
+	defer func(old bool) { strictMode = old }(strictMode)
+	nested := &Response{Status: "success", Result: map[string]any{
+		"targets": map[string]any{
+			"a#g1": map[string]any{"warnings": []string{"unconfirmed"}, "warning_codes": []string{warnUnconfirmed}},
+		},
+	}}
+
+	strictMode = false
+	if got := applyStrict(nested); got.Status != "success" {
+		t.Errorf("non-strict status = %s, want success", got.Status)
+	}
+	strictMode = true
+	got := applyStrict(nested)
+	if got.Status != "error" || got.Code != warnUnconfirmed {
+		t.Errorf("strict = %s/%s, want error/%s", got.Status, got.Code, warnUnconfirmed)
+	}
+	clean := &Response{Status: "success", Result: map[string]any{"cert_id": "1"}}
+	if got := applyStrict(clean); got != clean {
+		t.Errorf("strict without warnings changed the response")
+	}
+}
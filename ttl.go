@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// ttlParam 读取 ttl_seconds：未设置时为 0，设置时必须是正整数
+func ttlParam(cfg map[string]any) (int, error) {
+	if cfg["ttl_seconds"] == nil {
+		return 0, nil
+	}
+	ttl, err := intParam(cfg, "ttl_seconds", 0)
+	if err != nil || ttl <= 0 {
+		return 0, fmt.Errorf("ttl_seconds must be a positive integer")
+	}
+	return ttl, nil
+}
+
+// ttlQuery 返回创建/更新 SSL 对象时附加的 ?ttl= 查询参数。
+// 只有 APISIX 2.x 的 Admin API 支持 ttl（以 etcd lease 实现，到期后对象自动删除），
+// 3.x 已移除该参数，因此其他版本不发送
+func (a Auth) ttlQuery() string {
+	if a.TTLSeconds <= 0 || a.APIVersion != "v2" {
+		return ""
+	}
+	return "?ttl=" + strconv.Itoa(a.TTLSeconds)
+}
+
+// checkTTLSupport 在请求了 ttl_seconds 但目标不支持时追加告警，证书会一直保留直到被删除
+func checkTTLSupport(cfg map[string]any, b CertBackend, result map[string]any) {
+	if ttl, _ := ttlParam(cfg); ttl == 0 {
+		return
+	}
+	if a, ok := b.(*Auth); ok && a.APIVersion == "v2" {
+		return
+	}
+	addWarning(result, warnTTLIgnored, "ttl_seconds is only honored by the APISIX 2.x Admin API (api_version v2); the cert will not expire automatically")
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package main
+
+// restrictUmask 在不支持 umask 的平台上不做任何处理
+func restrictUmask() func() {
+	return func() {}
+}
@@ -0,0 +1,12 @@
+//go:build unix
+
+package main
+
+import "syscall"
+
+// restrictUmask 将进程 umask 设为 0077 并返回恢复函数，使随后创建的文件（如 socket）
+// 从创建起就只有当前用户可访问，不存在先创建后 chmod 的窗口
+func restrictUmask() func() {
+	old := syscall.Umask(0077)
+	return func() { syscall.Umask(old) }
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+)
+
+// UploadOnly 直接上传证书并返回新的 id，不查询也不删除网关上已有的证书，
+// 适用于自行管理证书生命周期的调用方
+func UploadOnly(cfg map[string]any) (*Response, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config cannot be nil")
+	}
+	certStr, ok := cfg["cert"].(string)
+	if !ok || certStr == "" {
+		return nil, fmt.Errorf("cert is required and must be a string")
+	}
+	keyStr, ok := cfg["key"].(string)
+	if !ok || keyStr == "" {
+		return nil, fmt.Errorf("key is required and must be a string")
+	}
+	domain, err := parseDomains(cfg["domain"])
+	if err != nil {
+		return nil, err
+	}
+	domain = normalizeDomains(domain)
+	keyType, err := validateKeyPair(certStr, keyStr)
+	if err != nil {
+		return nil, err
+	}
+	sha256, err := GetSHA256(certStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get SHA256 of cert: %w", err)
+	}
+	note, _ := cfg["desc"].(string)
+	if note == "" {
+		note = storedDesc(managedPrefix + sha256)
+	}
+	extra := map[string]any{}
+	labels, err := stringMapParam(cfg, "labels")
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := labels[managedByLabel]; ok {
+		return nil, fieldErrorf("labels."+managedByLabel, "is reserved for the managed-by marker")
+	}
+	if managedByField == "labels" {
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[managedByLabel] = managedPrefix + sha256
+	}
+	if len(labels) > 0 {
+		extra["labels"] = labels
+	}
+	certID, err := certIDParam(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if certID != "" {
+		extra["id"] = certID
+	}
+	extraFields, err := extraFieldsParam(cfg)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range extraFields {
+		extra[k] = v
+	}
+
+	a, err := backendFromParams(cfg)
+	if err != nil {
+		return nil, err
+	}
+	certKey, err := a.uploadCertToApisix(certStr, keyStr, note, domain, extra)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload cert to Apisix: %w", err)
+	}
+	result := map[string]any{
+		"cert_id":  certKey,
+		"key_type": keyType,
+		"snis":     domain,
+		"desc":     note,
+	}
+	attachDiagnostics(a, result)
+	return &Response{
+		Status:  "success",
+		Message: "Certificate uploaded successfully",
+		Result:  result,
+	}, nil
+}
+
+// BindOnly 将 domain 绑定到已上传的证书（cert_id），只更新 snis，不改动证书与私钥
+func BindOnly(cfg map[string]any) (*Response, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config cannot be nil")
+	}
+	certID, ok := cfg["cert_id"].(string)
+	if !ok || certID == "" {
+		return nil, fmt.Errorf("cert_id is required and must be a string")
+	}
+	domain, err := parseDomains(cfg["domain"])
+	if err != nil {
+		return nil, err
+	}
+	domain = normalizeDomains(domain)
+
+	a, err := backendFromParams(cfg)
+	if err != nil {
+		return nil, err
+	}
+	binder, ok := a.(sniBinder)
+	if !ok {
+		return nil, fmt.Errorf("bind_only is not supported by this backend")
+	}
+	value, err := findCert(a, certID)
+	if err != nil {
+		return nil, err
+	}
+	previous, _ := snisFromValue(value)
+	if err := binder.bindSNIs(certID, domain); err != nil {
+		return nil, fmt.Errorf("failed to bind snis to cert %s: %w", certID, err)
+	}
+	result := map[string]any{
+		"cert_id":       certID,
+		"snis":          domain,
+		"previous_snis": previous,
+	}
+	attachDiagnostics(a, result)
+	return &Response{
+		Status:  "success",
+		Message: "Domains bound successfully",
+		Result:  result,
+	}, nil
+}
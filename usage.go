@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// printVersion 输出插件名称、版本与构建信息（Go 版本、VCS 修订）
+func printVersion(w io.Writer) {
+	name, _ := pluginMeta["name"].(string)
+	version, _ := pluginMeta["version"].(string)
+	fmt.Fprintf(w, "%s %s\n", name, version)
+	fmt.Fprintf(w, "go: %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision", "vcs.time", "vcs.modified":
+				fmt.Fprintf(w, "%s: %s\n", s.Key, s.Value)
+			}
+		}
+	}
+}
+
+// printUsage 输出命令行用法、支持的动作及其参数，参数信息来自 metadata.json
+func printUsage(w io.Writer) {
+	description, _ := pluginMeta["description"].(string)
+	fmt.Fprintf(w, "%s\n\n", description)
+	fmt.Fprintf(w, "用法: echo '{\"action\": \"...\", \"params\": {...}}' | %s [flags]\n\nflags:\n", flag.CommandLine.Name())
+	flag.CommandLine.SetOutput(w)
+	flag.PrintDefaults()
+
+	fmt.Fprintf(w, "\n全局参数:\n")
+	writeSpecs(w, specsFromMeta(pluginMeta["config"]))
+	actions, _ := pluginMeta["actions"].([]any)
+	fmt.Fprintf(w, "\n动作:\n")
+	for _, item := range actions {
+		m, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		name, _ := m["name"].(string)
+		desc, _ := m["description"].(string)
+		fmt.Fprintf(w, "\n  %s\t%s\n", name, desc)
+		writeSpecs(w, specsFromMeta(m["params"]))
+	}
+}
+
+// writeSpecs 逐行输出参数名、类型、是否必填与说明
+func writeSpecs(w io.Writer, specs []paramSpec) {
+	for _, spec := range specs {
+		required := ""
+		if spec.Required {
+			required = "，必填"
+		}
+		fmt.Fprintf(w, "    %-24s %s%s\t%s\n", spec.Name, strings.ReplaceAll(spec.Type, "|", "/"), required, spec.Description)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// verify_before_delete 的默认等待时间与轮询间隔
+const (
+	defaultVerifyTimeout = 10 * time.Second
+	verifyPollInterval   = 500 * time.Millisecond
+)
+
+// waitForCert 轮询证书列表，直到 id 对应的证书存在、已启用且内容与 sha256 一致；
+// 超时或收到中断信号时返回错误
+func waitForCert(a CertBackend, id, sha256 string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	var lastErr error
+	for {
+		value, err := findCert(a, id)
+		switch {
+		case err != nil:
+			lastErr = err
+		case !certEnabled(value):
+			lastErr = fmt.Errorf("cert %s is disabled", id)
+		default:
+			stored, _ := value["cert"].(string)
+			if stored == "" {
+				return nil
+			}
+			if storedSHA256, err := GetSHA256(stored); err == nil && storedSHA256 == sha256 {
+				return nil
+			}
+			lastErr = fmt.Errorf("cert %s does not yet hold the uploaded certificate", id)
+		}
+		if time.Now().Add(verifyPollInterval).After(deadline) {
+			return fmt.Errorf("new cert %s not confirmed within %s: %w", id, timeout, lastErr)
+		}
+		select {
+		case <-interruptCtx.Done():
+			return fmt.Errorf("interrupted while confirming new cert %s: %w", id, lastErr)
+		case <-time.After(verifyPollInterval):
+		}
+	}
+}
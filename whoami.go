@@ -0,0 +1,157 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// redact 隐藏敏感信息，仅保留首尾各 2 个字符
+func redact(secret string) string {
+	if len(secret) <= 6 {
+		return strings.Repeat("*", len(secret))
+	}
+	return secret[:2] + strings.Repeat("*", len(secret)-4) + secret[len(secret)-2:]
+}
+
+// Whoami 返回解析后的目标地址、认证来源、超时与后端模式，admin_key 脱敏；
+// 设置 ping 时额外发起一次只读请求验证连通性
+func Whoami(cfg map[string]any) (*Response, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config cannot be nil")
+	}
+	backend, _ := cfg["backend"].(string)
+	if backend == "" {
+		backend = "admin_api"
+	}
+	result := map[string]any{"backend": backend}
+
+	b, err := backendFromParams(cfg)
+	if err != nil {
+		return nil, err
+	}
+	switch v := b.(type) {
+	case *Auth:
+		result["server_address"] = v.ServerAddress + v.AdminPrefix
+		result["admin_key"] = redact(v.AdminKey)
+		result["admin_key_source"] = v.KeySource
+		result["auth_scheme"] = "x-api-key"
+		if v.AuthScheme != "" {
+			result["auth_scheme"] = v.AuthScheme
+		}
+		result["api_version"] = v.APIVersion
+		if v.APIDetected {
+			result["api_version_detected"] = true
+			result["legacy_sni"] = v.LegacySNI
+		}
+		result["timeout_seconds"] = v.Timeout.Seconds()
+		result["max_retries"] = v.MaxRetries
+		if v.BasicUser != "" {
+			result["basic_user"] = v.BasicUser
+			result["basic_pass"] = redact(v.BasicPass)
+		}
+	case *Dashboard:
+		result["server_address"] = v.ServerAddress
+		result["username"] = v.Username
+	}
+
+	if ping, _ := cfg["ping"].(bool); ping {
+		start := time.Now()
+		var err error
+		if p, ok := b.(pageLister); ok {
+			_, _, err = p.listCertPage(1, minPageSize)
+		} else {
+			_, err = b.listCertFromApisix()
+		}
+		pingResult := map[string]any{
+			"ok":         err == nil,
+			"latency_ms": float64(time.Since(start).Microseconds()) / 1000,
+		}
+		if err != nil {
+			pingResult["error"] = err.Error()
+		}
+		result["ping"] = pingResult
+	}
+	return &Response{
+		Status:  "success",
+		Message: "Effective target resolved",
+		Result:  result,
+	}, nil
+}
+
+// TestAdminKey 使用候选 admin_key 发起一次只读的 /ssls 请求，验证轮换后的新 key 是否可用；
+// 不做任何修改，输出中的新旧 key 均脱敏
+func TestAdminKey(cfg map[string]any) (*Response, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config cannot be nil")
+	}
+	candidate, ok := cfg["new_admin_key"].(string)
+	if !ok || candidate == "" {
+		return nil, fmt.Errorf("new_admin_key is required and must be a string")
+	}
+	a, err := authFromParams(cfg)
+	if err != nil {
+		return nil, err
+	}
+	current := a.AdminKey
+	a.AdminKey = candidate
+	// 只验证 key 是否被接受，不因限流反复重试
+	a.MaxRetries = 0
+
+	start := time.Now()
+	_, _, err = a.listCertPage(1, minPageSize)
+	result := map[string]any{
+		"server_address": a.ServerAddress + a.AdminPrefix,
+		"admin_key":      redact(current),
+		"new_admin_key":  redact(candidate),
+		"valid":          err == nil,
+		"latency_ms":     float64(time.Since(start).Microseconds()) / 1000,
+	}
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		result["http_status"] = apiErr.StatusCode
+		if apiErr.StatusCode != http.StatusUnauthorized && apiErr.StatusCode != http.StatusForbidden {
+			return nil, fmt.Errorf("could not verify new_admin_key: %w", err)
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("could not verify new_admin_key: %w", err)
+	}
+	message := "New admin key is valid"
+	if err != nil {
+		result["error"] = err.Error()
+		message = "New admin key was rejected"
+	}
+	attachDiagnostics(a, result)
+	return &Response{
+		Status:  "success",
+		Message: message,
+		Result:  result,
+	}, nil
+}
+
+// redactSecrets 递归复制响应数据，将私钥内容替换为占位符
+func redactSecrets(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for k, item := range val {
+			out[k] = redactSecrets(item)
+		}
+		return out
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			out[i] = redactSecrets(item)
+		}
+		return out
+	case string:
+		if strings.Contains(val, "PRIVATE KEY") {
+			return "[REDACTED]"
+		}
+		return val
+	default:
+		return val
+	}
+}